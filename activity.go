@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rakyll/portmidi"
+)
+
+// channelCounts counts the events seen on a single MIDI channel.
+type channelCounts struct {
+	notes, controlChanges, programChanges, pitchBends, other int
+	// controllers counts the control changes for each controller number.
+	controllers map[int]int
+}
+
+// channelActivity is a pipeline stage that passes events through
+// unchanged while keeping statistics of them, to show which channels (and
+// so which parts) are in use.
+type channelActivity struct {
+	mu       sync.Mutex
+	channels [16]channelCounts
+	sysEx    int
+}
+
+func (a *channelActivity) apply(e portmidi.Event) []portmidi.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.add(e)
+	return []portmidi.Event{e}
+}
+
+// add counts an event.
+func (a *channelActivity) add(e portmidi.Event) {
+	if e.SysEx != nil {
+		a.sysEx++
+		return
+	}
+	if !isChannelMessage(e) {
+		return
+	}
+	c := &a.channels[eventChannel(e)-1]
+	switch e.Status & 0xf0 {
+	case statusNoteOn:
+		// A note on with zero velocity is really a note off.
+		if e.Data2 > 0 {
+			c.notes++
+		}
+	case statusNoteOff:
+	case statusControlChange:
+		c.controlChanges++
+		if c.controllers == nil {
+			c.controllers = map[int]int{}
+		}
+		c.controllers[int(e.Data1)]++
+	case statusProgramChange:
+		c.programChanges++
+	case statusPitchBend:
+		c.pitchBends++
+	default:
+		c.other++
+	}
+}
+
+// busiestControllers returns the controller numbers that were used, most
+// used first, formatted as "<controller>:<count>".
+func (c *channelCounts) busiestControllers() string {
+	ccs := []int{}
+	for cc := range c.controllers {
+		ccs = append(ccs, cc)
+	}
+	sort.Slice(ccs, func(i, j int) bool {
+		ni, nj := c.controllers[ccs[i]], c.controllers[ccs[j]]
+		if ni != nj {
+			return ni > nj
+		}
+		return ccs[i] < ccs[j]
+	})
+	result := []string{}
+	for _, cc := range ccs {
+		result = append(result, fmt.Sprintf("%d:%d", cc, c.controllers[cc]))
+	}
+	return strings.Join(result, " ")
+}
+
+// summary writes a table of the events counted on each channel that was
+// used.
+func (a *channelActivity) summary(w io.Writer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fmt.Fprintf(w, "%-4s %7s %7s %7s %7s %7s  %s\n", "ch", "notes", "cc", "program", "bend", "other", "controllers (number:count)")
+	used := 0
+	for i := range a.channels {
+		c := &a.channels[i]
+		if c.notes+c.controlChanges+c.programChanges+c.pitchBends+c.other == 0 {
+			continue
+		}
+		used++
+		fmt.Fprintf(w, "%-4d %7d %7d %7d %7d %7d  %s\n", i+1, c.notes, c.controlChanges, c.programChanges, c.pitchBends, c.other, c.busiestControllers())
+	}
+	fmt.Fprintf(w, "%d of 16 channels used, %d SysEx messages\n", used, a.sysEx)
+}
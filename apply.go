@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+func setApplyFlags(f *flag.FlagSet, verify *bool, report *string) {
+	f.BoolVar(verify, "verify", false, "read back each register after writing it to check the value was accepted")
+	f.StringVar(report, "failure_report", "", "file to write a JSON report of any registers that failed")
+}
+
+// openApplyStreams opens the output stream, and the input stream too if
+// writes are to be verified.
+func openApplyStreams(verify bool) (*portmidi.Stream, *portmidi.Stream, error) {
+	out, err := openOutputStream()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !verify {
+		return nil, out, nil
+	}
+	in, err := openInputStream()
+	if err != nil {
+		return nil, nil, err
+	}
+	return in, out, nil
+}
+
+// applySettings writes the settings in the given file to the device,
+// reporting any registers that failed.
+func applySettings(sf *settingsFile, verify bool, report string) subcommands.ExitStatus {
+	in, out, err := openApplyStreams(verify)
+	if err != nil {
+		log.Printf("failed to open streams: %v", err)
+		return subcommands.ExitFailure
+	}
+	if failures := writeSettings(in, out, sf, report); len(failures) > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// writeSettings writes the registers and text registers in sf to the
+// device, saving any failures to the report file if one is given, and
+// returns the failures.
+func writeSettings(in, out *portmidi.Stream, sf *settingsFile, report string) failureReport {
+	failures := sf.Registers.apply(in, out, deviceID())
+	failures = append(failures, applyText(out, deviceID(), sf.Text)...)
+	if report != "" {
+		if err := failures.save(report); err != nil {
+			log.Printf("failed to write failure report: %v", err)
+		}
+	}
+	if len(failures) > 0 {
+		log.Printf("%d of %d registers failed:", len(failures), len(sf.Registers)+len(sf.Text))
+		failures.log()
+	}
+	return failures
+}
+
+type applyCommand struct {
+	verify   bool
+	report   string
+	watch    bool
+	interval time.Duration
+}
+
+func (*applyCommand) Name() string     { return "apply" }
+func (*applyCommand) Synopsis() string { return "write the register values in a settings file" }
+func (*applyCommand) Usage() string    { return "apply <settings.json>:\n" }
+
+func (c *applyCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setApplyFlags(f, &c.verify, &c.report)
+	setDisplayErrorFlags(f)
+	f.BoolVar(&c.watch, "watch", false, "keep running, and apply changed registers whenever the file is saved")
+	f.DurationVar(&c.interval, "watch_interval", 250*time.Millisecond, "how often to check the file's modification time when watching; a save is applied up to this long after it happens")
+}
+
+// changedSettings returns the settings in s that have different values to
+// those in prev, or aren't in prev at all.
+func changedSettings(prev, s settings) settings {
+	result := settings{}
+	for name, value := range s {
+		if old, ok := prev[name]; !ok || old != value {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// changedText is like changedSettings, for the values of text registers.
+func changedText(prev, text map[string]string) map[string]string {
+	result := map[string]string{}
+	for name, value := range text {
+		if old, ok := prev[name]; !ok || old != value {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// watchFile applies the file and then polls it for changes, applying only the
+// registers and text registers that changed each time it's saved. The file is polled with a
+// stat every -watch_interval rather than watched with inotify or similar,
+// which would need another dependency. A stat is cheap, so the default of
+// 250ms costs next to nothing while still applying a save almost at once;
+// a change is only read and applied when the modification time moves, and
+// a save is noticed up to one interval late.
+func (c *applyCommand) watchFile(filename string) subcommands.ExitStatus {
+	in, out, err := openApplyStreams(c.verify)
+	if err != nil {
+		log.Printf("failed to open streams: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer displayPanics(out)
+	prev := &settingsFile{Registers: settings{}, Text: map[string]string{}}
+	var lastModified time.Time
+	for {
+		time.Sleep(c.interval)
+		fi, err := os.Stat(filename)
+		if err != nil {
+			log.Printf("failed to stat %s: %v", filename, err)
+			continue
+		}
+		if fi.ModTime().Equal(lastModified) {
+			continue
+		}
+		lastModified = fi.ModTime()
+		sf, err := loadSettingsFile(filename)
+		if err != nil {
+			// Probably a typo mid-edit; wait for the next save.
+			log.Printf("failed to load settings: %v", err)
+			continue
+		}
+		changed := &settingsFile{
+			Registers: changedSettings(prev.Registers, sf.Registers),
+			Text:      changedText(prev.Text, sf.Text),
+		}
+		if len(changed.Registers)+len(changed.Text) == 0 {
+			continue
+		}
+		log.Printf("applying %d changed registers", len(changed.Registers)+len(changed.Text))
+		failures := writeSettings(in, out, changed, c.report)
+		prev = sf
+		if prev.Text == nil {
+			prev.Text = map[string]string{}
+		}
+		for _, failure := range failures {
+			// Try again on the next save.
+			delete(prev.Registers, failure.Register)
+			delete(prev.Text, failure.Register)
+		}
+	}
+}
+
+func (c *applyCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("settings file not provided")
+		return subcommands.ExitUsageError
+	}
+	if c.watch {
+		return c.watchFile(f.Args()[0])
+	}
+	sf, err := loadSettingsFile(f.Args()[0])
+	if err != nil {
+		log.Printf("failed to load settings: %v", err)
+		return subcommands.ExitFailure
+	}
+	return applySettings(sf, c.verify, c.report)
+}
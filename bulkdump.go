@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+type bulkDumpCommand struct {
+	timeout      time.Duration
+	restore      bool
+	settingsFile string
+}
+
+func (*bulkDumpCommand) Name() string { return "bulk-dump" }
+func (*bulkDumpCommand) Synopsis() string {
+	return "save the whole device state to a .syx file using a bulk dump, or restore it"
+}
+func (*bulkDumpCommand) Usage() string {
+	return `bulk-dump [-restore] <file.syx>:
+Requests a bulk dump of all system and part parameters and saves the
+reply to the given file. With -restore, sends a saved dump back to the
+device instead. With -settings, the register values in the dump are also
+decoded and saved as a settings file, as written by checkpoint.
+`
+}
+
+func (c *bulkDumpCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.DurationVar(&c.timeout, "timeout", 5*time.Second, "how long to wait for the whole dump to be received")
+	f.BoolVar(&c.restore, "restore", false, "send the dump in the file to the device")
+	f.StringVar(&c.settingsFile, "settings", "", "also save the register values in the dump to this settings file")
+}
+
+// bulkDumpSettings decodes the register values held in a bulk dump, in the
+// same form as fetchSettings.
+func bulkDumpSettings(d *sc55.BulkDump) (settings, error) {
+	values, err := d.Values(sc55.DefaultRegistry())
+	if err != nil {
+		return nil, err
+	}
+	s := settings{}
+	for r, v := range values {
+		s[r.Name()] = v
+	}
+	return s, nil
+}
+
+// fetchBulkDump requests a bulk dump and waits for all of it to arrive.
+func fetchBulkDump(in, out *portmidi.Stream, device sc55.DeviceID, timeout time.Duration) (*sc55.BulkDump, error) {
+	if err := writeSysEx(out, sc55.BulkDumpRequest(device)); err != nil {
+		return nil, err
+	}
+	d := sc55.NewBulkDump()
+	timeoutTime := time.Now().Add(timeout)
+	for !d.Complete() {
+		msg, err := readSysEx(in)
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) == 0 {
+			if time.Now().After(timeoutTime) {
+				return nil, fmt.Errorf("timeout waiting for bulk dump")
+			}
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		if _, err := d.Add(device, msg); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+func (c *bulkDumpCommand) save(filename string) subcommands.ExitStatus {
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	d, err := fetchBulkDump(in, out, deviceID(), c.timeout)
+	if err != nil {
+		log.Printf("failed to fetch bulk dump: %v", err)
+		return subcommands.ExitFailure
+	}
+	data := []byte{}
+	for _, msg := range d.Messages() {
+		data = append(data, msg...)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		log.Printf("failed to write file: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("bulk dump of %d bytes saved to %s", len(d.Data()), filename)
+	if c.settingsFile == "" {
+		return subcommands.ExitSuccess
+	}
+	s, err := bulkDumpSettings(d)
+	if err != nil {
+		log.Printf("failed to decode bulk dump: %v", err)
+		return subcommands.ExitFailure
+	}
+	f := &settingsFile{Registers: s}
+	if err := f.save(c.settingsFile); err != nil {
+		log.Printf("failed to save settings: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("%d register values saved to %s", len(s), c.settingsFile)
+	return subcommands.ExitSuccess
+}
+
+func (c *bulkDumpCommand) load(filename string) subcommands.ExitStatus {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		log.Printf("failed to read file: %v", err)
+		return subcommands.ExitFailure
+	}
+	// The messages are all checked before anything is sent, so that a
+	// damaged file is not half-applied.
+	d := sc55.NewBulkDump()
+	for _, msg := range sc55.SplitSysEx(data) {
+		dev, _, _, err := sc55.UnmarshalSet(msg)
+		if err != nil {
+			log.Printf("invalid message in bulk dump: %v", err)
+			return subcommands.ExitFailure
+		}
+		if ok, err := d.Add(dev, msg); !ok || err != nil {
+			log.Printf("message in %s is not part of a bulk dump: % x", filename, msg)
+			return subcommands.ExitFailure
+		}
+	}
+	if !d.Complete() {
+		log.Printf("bulk dump in %s is incomplete", filename)
+		return subcommands.ExitFailure
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	for _, msg := range sc55.SplitDataSet(deviceID(), sc55.AddrBulkDump, d.Data()...) {
+		if err := writeSysEx(out, msg); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+		time.Sleep(bulkWriteDelay)
+	}
+	return subcommands.ExitSuccess
+}
+
+func (c *bulkDumpCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	if c.restore {
+		return c.load(f.Args()[0])
+	}
+	return c.save(f.Args()[0])
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type buttonCommand struct{}
+
+func (*buttonCommand) Name() string { return "button" }
+func (*buttonCommand) Synopsis() string {
+	return "press a front panel button by SysEx (needs custom_registers entries in the config file)"
+}
+func (*buttonCommand) Usage() string {
+	return `button <name> [value]:
+Simulates pressing a front panel button (eg. mute, part select or all),
+for operating a rack-mounted unit that can't be reached. The value sent
+is the register's maximum unless another is given, eg. the part number
+for a part select button.
+
+None of the built-in profiles has registers for these "remote switch"
+messages, because they aren't documented for any of those products. The
+command only works once registers named "` + sc55.ButtonRegisterPrefix + `<name>" have been added to
+the custom_registers list in the config file, for firmware that responds
+to them, eg:
+
+  "custom_registers": [
+    {"name": "` + sc55.ButtonRegisterPrefix + `mute", "address": "...", "size": 1, "min": 0, "max": 1}
+  ]
+
+Running button with no arguments lists the buttons that are defined.
+`
+}
+
+func (*buttonCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+}
+
+func (c *buttonCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	p := sc55.CurrentProfile()
+	args := f.Args()
+	switch {
+	case len(args) == 0:
+		buttons := p.Buttons()
+		if len(buttons) == 0 {
+			log.Printf("the %s has no buttons that can be pressed by SysEx; add custom_registers entries named %q to %s to use this command", p.Description, sc55.ButtonRegisterPrefix+"<name>", configFilename())
+			return subcommands.ExitFailure
+		}
+		log.Printf("buttons: %s", strings.Join(buttons, ", "))
+		return subcommands.ExitSuccess
+	case len(args) > 2:
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	r, err := p.ButtonRegister(args[0])
+	if err != nil {
+		log.Printf("%v; add a custom_registers entry named %q to %s to use this command", err, sc55.ButtonRegisterPrefix+args[0], configFilename())
+		return subcommands.ExitFailure
+	}
+	_, value := r.Range()
+	if len(args) > 1 {
+		v, err := strconv.Atoi(args[1])
+		if err != nil {
+			log.Printf("invalid value %q: %v", args[1], err)
+			return subcommands.ExitUsageError
+		}
+		if err := r.CheckRange(v); err != nil {
+			log.Printf("%v", err)
+			return subcommands.ExitUsageError
+		}
+		value = v
+	}
+	msg, err := r.Set(deviceID(), value)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitFailure
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	if err := writeSysEx(out, msg); err != nil {
+		log.Printf("failed to write message to output: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
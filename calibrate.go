@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+type calibrateCommand struct {
+	burst    int
+	maxDelay time.Duration
+	margin   float64
+	save     bool
+	verbose  bool
+}
+
+func (*calibrateCommand) Name() string { return "calibrate" }
+func (*calibrateCommand) Synopsis() string {
+	return "find the shortest safe delay between messages for bulk operations"
+}
+func (*calibrateCommand) Usage() string {
+	return `calibrate:
+Sends bursts of writes to the tone modify registers with different delays
+between messages, reading them back each time to check that none were
+lost, and searches for the shortest delay that works. The result is saved
+to the config file and used by later bulk operations. The registers are
+restored to their previous values afterwards.
+`
+}
+
+func (c *calibrateCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.IntVar(&c.burst, "burst", 64, "number of messages to send in each burst")
+	f.DurationVar(&c.maxDelay, "max_delay", 100*time.Millisecond, "longest delay to try; calibration fails if this doesn't work")
+	f.Float64Var(&c.margin, "margin", 0.5, "safety margin to add to the measured delay, as a fraction of it")
+	f.BoolVar(&c.save, "save", true, "save the result to the config file")
+	f.BoolVar(&c.verbose, "verbose", false, "log the result of each delay tried")
+}
+
+// calibrationRegisters returns the registers written during calibration.
+// The tone modify registers are used since a brief change to them is
+// harmless and they lie in a handful of blocks that read back quickly.
+func calibrationRegisters(n int) []*sc55.Register {
+	regs := []*sc55.Register{}
+	for i := 1; i <= sc55.PartCount() && len(regs) < n; i++ {
+		p := sc55.PartByNumber(i)
+		for _, r := range []*sc55.Register{
+			&p.ToneModify1, &p.ToneModify2, &p.ToneModify3, &p.ToneModify4,
+			&p.ToneModify5, &p.ToneModify6, &p.ToneModify7, &p.ToneModify8,
+		} {
+			if len(regs) < n {
+				regs = append(regs, r)
+			}
+		}
+	}
+	return regs
+}
+
+// tryDelay writes a burst of new values with the given delay between
+// messages and returns true if they were all received. Each trial uses
+// different values from the one before, so that a lost message always
+// shows up as a wrong value.
+func tryDelay(in, out *portmidi.Stream, regs []*sc55.Register, trial int, delay time.Duration) (bool, error) {
+	want := map[*sc55.Register]int{}
+	for i, r := range regs {
+		min, _ := r.Range()
+		want[r] = min + (trial+i)%3
+		msg, err := r.Set(deviceID(), want[r])
+		if err != nil {
+			return false, err
+		}
+		if err := writeSysEx(out, msg); err != nil {
+			return false, err
+		}
+		time.Sleep(delay)
+	}
+	values, errs := queryRegisters(in, out, deviceID(), regs, replyTimeout)
+	for _, r := range regs {
+		if errs[r] != nil || values[r] != want[r] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// calibrate searches for the shortest delay up to -max_delay that passes
+// tryDelay, to a resolution of a millisecond.
+func (c *calibrateCommand) calibrate(in, out *portmidi.Stream, regs []*sc55.Register) (time.Duration, error) {
+	lo, hi := time.Duration(0), c.maxDelay
+	trial := 0
+	ok, err := tryDelay(in, out, regs, trial, hi)
+	switch {
+	case err != nil:
+		return 0, err
+	case !ok:
+		return 0, fmt.Errorf("messages were lost even with a delay of %v", hi)
+	}
+	for hi-lo > time.Millisecond {
+		trial++
+		mid := (lo + hi) / 2
+		ok, err := tryDelay(in, out, regs, trial, mid)
+		if err != nil {
+			return 0, err
+		}
+		if c.verbose {
+			log.Printf("delay %v: ok=%v", mid, ok)
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return hi, nil
+}
+
+func (c *calibrateCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	regs := calibrationRegisters(c.burst)
+	orig, _, err := fetchSettings(in, out, regs, replyTimeout)
+	if err != nil {
+		log.Printf("failed to read current values: %v", err)
+		return subcommands.ExitFailure
+	}
+	delay, err := c.calibrate(in, out, regs)
+	// Put things back with the slowest delay, which is known to work.
+	bulkWriteDelay = c.maxDelay
+	if failures := orig.apply(nil, out, deviceID()); len(failures) > 0 {
+		log.Printf("failed to restore registers:")
+		failures.log()
+	}
+	if err != nil {
+		log.Printf("calibration failed: %v", err)
+		return subcommands.ExitFailure
+	}
+	delay += time.Duration(float64(delay) * c.margin)
+	delay = delay.Round(time.Millisecond)
+	fmt.Printf("shortest safe delay: %v\n", delay)
+	if !c.save {
+		return subcommands.ExitSuccess
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		log.Printf("failed to load config: %v", err)
+		return subcommands.ExitFailure
+	}
+	cfg.WriteDelay = delay.String()
+	if err := cfg.save(); err != nil {
+		log.Printf("failed to save config: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("saved to %s", configFilename())
+	return subcommands.ExitSuccess
+}
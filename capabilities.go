@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+// capabilities describes what this build of the program supports, for
+// front-ends that drive it.
+type capabilities struct {
+	Version  string   `json:"version"`
+	Platform string   `json:"platform"`
+	Backends []string `json:"backends"`
+	// Models are the SoundCanvas models and products that can be passed
+	// to -model.
+	Models       []string `json:"models"`
+	CurrentModel string   `json:"current_model"`
+	Commands     []string `json:"commands"`
+	// Transforms are the transform types that can be used in a proxy
+	// pipeline file.
+	Transforms []string `json:"transforms"`
+	Tags       []string `json:"tags"`
+	Groups     []string `json:"groups"`
+	// Features lists optional behavior that front-ends may want to
+	// check for before relying on it.
+	Features []string `json:"features"`
+	Ports    []port   `json:"ports"`
+}
+
+// port is a MIDI port found by the MIDI backend.
+type port struct {
+	Name   string `json:"name"`
+	Input  bool   `json:"input"`
+	Output bool   `json:"output"`
+}
+
+// features are the names reported in the features list.
+var features = []string{
+	"adaptive-timeout",
+	"bulk-dump",
+	"channel-activity",
+	"cc-slew",
+	"custom-registers",
+	"emulator-detection",
+	"model-probe",
+	"profiles",
+	"settings-migration",
+	"read-only",
+	"retries",
+	"strict",
+	"value-names",
+}
+
+func currentCapabilities() capabilities {
+	c := capabilities{
+		Version:      versionString(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		Backends:     []string{"portmidi"},
+		Models:       []string{},
+		CurrentModel: sc55.CurrentProfile().Name,
+		Commands:     []string{},
+		Transforms:   []string{},
+		Tags:         tagNames(sc55.AllTags()),
+		Groups:       groupNames(sc55.AllGroups()),
+		Features:     features,
+		Ports:        []port{},
+	}
+	for _, p := range sc55.AllProfiles() {
+		c.Models = append(c.Models, p.Name)
+	}
+	for _, cmd := range commands {
+		c.Commands = append(c.Commands, cmd.Name())
+	}
+	sort.Strings(c.Commands)
+	for name := range transformTypes {
+		c.Transforms = append(c.Transforms, name)
+	}
+	sort.Strings(c.Transforms)
+	for i := 0; i < portmidi.CountDevices(); i++ {
+		info := portmidi.Info(portmidi.DeviceID(i))
+		c.Ports = append(c.Ports, port{info.Name, info.IsInputAvailable, info.IsOutputAvailable})
+	}
+	return c
+}
+
+type capabilitiesCommand struct{}
+
+func (*capabilitiesCommand) Name() string { return "capabilities" }
+func (*capabilitiesCommand) Synopsis() string {
+	return "print what this build supports as JSON, for front-ends"
+}
+func (*capabilitiesCommand) Usage() string {
+	return `capabilities:
+Prints a JSON object listing the version, the MIDI backends, models,
+commands, proxy transforms and other features supported by this build, and
+the MIDI ports that are currently available.
+`
+}
+func (*capabilitiesCommand) SetFlags(f *flag.FlagSet) {}
+
+func (*capabilitiesCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	data, err := json.MarshalIndent(currentCapabilities(), "", "  ")
+	if err != nil {
+		log.Printf("failed to marshal capabilities: %v", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Println(string(data))
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// defaultCheckpointFile returns the path where checkpoints are saved when no
+// filename is given on the command line.
+func defaultCheckpointFile() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sc55ctl", "checkpoint.json")
+}
+
+func checkpointFilename(f *flag.FlagSet) string {
+	if len(f.Args()) > 0 {
+		return f.Args()[0]
+	}
+	return defaultCheckpointFile()
+}
+
+// setCheckpointFlag adds the -checkpoint flag. Commands that write many
+// registers at once, which would be tedious to undo by hand, turn it on by
+// default.
+func setCheckpointFlag(f *flag.FlagSet, checkpoint *bool, byDefault bool) {
+	help := "save the device state first, so that the change can be undone with rollback"
+	if byDefault {
+		help += "; on by default, use -checkpoint=false to skip it"
+	}
+	f.BoolVar(checkpoint, "checkpoint", byDefault, help)
+}
+
+// saveCheckpoint reads the state of every register on the device and saves
+// it to the given file.
+func saveCheckpoint(filename string) error {
+	in, err := openInputStream()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	s, skipped, err := fetchSettings(in, out, sc55.AllRegisters(), replyTimeout)
+	if err != nil {
+		return err
+	}
+	f := &settingsFile{Registers: s, Text: fetchText(in, out, replyTimeout)}
+	for _, b := range skipped {
+		f.Skipped = append(f.Skipped, skippedBlock{fmt.Sprintf("%06x", b.Address), b.Size})
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return f.save(filename)
+}
+
+type checkpointCommand struct{}
+
+func (*checkpointCommand) Name() string { return "checkpoint" }
+func (*checkpointCommand) Synopsis() string {
+	return "save the state of all registers so that it can be restored with rollback"
+}
+func (*checkpointCommand) Usage() string { return "checkpoint [file]:\n" }
+
+func (*checkpointCommand) SetFlags(f *flag.FlagSet) { setCommonFlags(f) }
+
+func (*checkpointCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	filename := checkpointFilename(f)
+	if err := saveCheckpoint(filename); err != nil {
+		log.Printf("failed to save checkpoint: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("checkpoint saved to %s", filename)
+	return subcommands.ExitSuccess
+}
+
+type rollbackCommand struct {
+	verify bool
+	report string
+}
+
+func (*rollbackCommand) Name() string { return "rollback" }
+func (*rollbackCommand) Synopsis() string {
+	return "restore the register state saved by checkpoint"
+}
+func (*rollbackCommand) Usage() string { return "rollback [file]:\n" }
+
+func (c *rollbackCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setApplyFlags(f, &c.verify, &c.report)
+}
+
+func (c *rollbackCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	sf, err := loadSettingsFile(checkpointFilename(f))
+	if err != nil {
+		log.Printf("failed to load checkpoint: %v", err)
+		return subcommands.ExitFailure
+	}
+	return applySettings(sf, c.verify, c.report)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+)
+
+// config holds settings that are remembered between runs, such as the
+// results of calibrate.
+type config struct {
+	// WriteDelay is the pause between messages in bulk operations, as a
+	// duration string like "20ms".
+	WriteDelay string `json:"write_delay,omitempty"`
+	// PollIntervals sets how often registers with each tag are read by
+	// commands that watch a device, eg. {"front-panel": "2s"}.
+	PollIntervals map[string]string `json:"poll_intervals,omitempty"`
+	// CustomRegisters are extra registers to add to the built-in ones,
+	// for addresses that aren't documented or are specific to a device.
+	CustomRegisters []customRegister `json:"custom_registers,omitempty"`
+	// Model is the name of the profile to use when -model isn't given,
+	// as saved by identify.
+	Model string `json:"model,omitempty"`
+}
+
+// customRegister is the definition of a register in the config file.
+type customRegister struct {
+	Name string `json:"name"`
+	// Address is in hex, as shown by list.
+	Address string `json:"address"`
+	Size    int    `json:"size"`
+	Min     int    `json:"min"`
+	Max     int    `json:"max"`
+	Zero    int    `json:"zero"`
+	// Model is the earliest model with the register; sc55 if empty.
+	Model string   `json:"model,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// Nibbles marks a nibblized register (4 bits per byte).
+	Nibbles bool `json:"nibbles,omitempty"`
+}
+
+// add adds the register to the register tables.
+func (c customRegister) add() error {
+	addr, err := strconv.ParseInt(strings.TrimPrefix(c.Address, "0x"), 16, 32)
+	if err != nil {
+		return fmt.Errorf("register %q: invalid address %q", c.Name, c.Address)
+	}
+	m := sc55.ModelSC55
+	if c.Model != "" {
+		var ok bool
+		if m, ok = sc55.ModelByName(c.Model); !ok {
+			return fmt.Errorf("register %q: unknown model %q", c.Name, c.Model)
+		}
+	}
+	tags, err := sc55.ParseTags(strings.Join(c.Tags, ","))
+	if err != nil {
+		return fmt.Errorf("register %q: %v", c.Name, err)
+	}
+	size := c.Size
+	if size == 0 {
+		size = 1
+	}
+	e := sc55.EncodingBytes
+	if c.Nibbles {
+		e = sc55.EncodingNibbles
+	}
+	r := &sc55.Register{Address: int(addr), Size: size, Min: c.Min, Max: c.Max, Zero: c.Zero}
+	return sc55.AddRegister(c.Name, r, m, e, tags...)
+}
+
+// configFilename returns the path of the config file.
+func configFilename() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "sc55ctl", "config.json")
+}
+
+// loadConfig reads the config file. A missing file is not an error; the
+// defaults are used.
+func loadConfig() (*config, error) {
+	c := &config{}
+	data, err := os.ReadFile(configFilename())
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return c, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", configFilename(), err)
+	}
+	return c, nil
+}
+
+func (c *config) save() error {
+	filename := configFilename()
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// applyConfig loads the config file and applies its settings.
+func applyConfig() error {
+	c, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if c.WriteDelay != "" {
+		d, err := time.ParseDuration(c.WriteDelay)
+		if err != nil {
+			return fmt.Errorf("invalid write_delay %q in %s: %v", c.WriteDelay, configFilename(), err)
+		}
+		bulkWriteDelay = d
+	}
+	pollIntervals, err = parsePollIntervals(c.PollIntervals)
+	if err != nil {
+		return fmt.Errorf("invalid poll_intervals in %s: %v", configFilename(), err)
+	}
+	if c.Model != "" && !modelGiven {
+		p, ok := sc55.ProfileByName(c.Model)
+		if !ok {
+			return fmt.Errorf("unknown model %q in %s", c.Model, configFilename())
+		}
+		sc55.SetProfile(p)
+	}
+	for _, r := range c.CustomRegisters {
+		if err := r.add(); err != nil {
+			return fmt.Errorf("invalid custom register in %s: %v", configFilename(), err)
+		}
+	}
+	return nil
+}
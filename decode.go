@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// describeMessage returns a human-readable description of a SysEx message.
+func describeMessage(msg []byte) string {
+	if sc55.Classify(msg) == sc55.MessageRQ1 {
+		dev, addr, size, err := sc55.UnmarshalGet(msg)
+		if err != nil {
+			return fmt.Sprintf("% x (%v)", msg, err)
+		}
+		if r, ok := sc55.RegisterByAddress(addr); ok && r.Size == size {
+			return fmt.Sprintf("[%02x] get %s", dev, r.Name())
+		}
+		return fmt.Sprintf("[%02x] RQ1 %06x: %d bytes", dev, addr, size)
+	}
+	dev, addr, data, err := sc55.UnmarshalSet(msg)
+	if err != nil {
+		return fmt.Sprintf("% x (%v)", msg, err)
+	}
+	if addr&0xff0000 == sc55.AddrBulkDump {
+		return fmt.Sprintf("[%02x] bulk-dump %06x: %d bytes", dev, addr, len(data))
+	}
+	if addr == sc55.AddrDisplayMessage {
+		return fmt.Sprintf("[%02x] display-message %q", dev, sc55.DecodeDisplayMessage(data))
+	}
+	for _, t := range sc55.AllTextRegisters() {
+		if t.Address != addr {
+			continue
+		}
+		if value, err := t.Decode(data); err == nil {
+			return fmt.Sprintf("[%02x] %s = %q", dev, t.Name(), value)
+		}
+	}
+	if r, ok := sc55.RegisterByAddress(addr); ok {
+		if _, value, err := r.Unmarshal(msg); err == nil {
+			return fmt.Sprintf("[%02x] %s = %s", dev, r.Name(), r.FormatValue(value))
+		}
+	}
+	return fmt.Sprintf("[%02x] DT1 %06x: % x", dev, addr, data)
+}
+
+type decodeCommand struct{}
+
+func (*decodeCommand) Name() string     { return "decode" }
+func (*decodeCommand) Synopsis() string { return "describe the SC-55 messages in a .syx file" }
+func (*decodeCommand) Usage() string    { return "decode <file.syx>:\n" }
+
+func (*decodeCommand) SetFlags(*flag.FlagSet) {}
+
+func (*decodeCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("file to decode not provided")
+		return subcommands.ExitUsageError
+	}
+	data, err := os.ReadFile(f.Args()[0])
+	if err != nil {
+		log.Printf("failed to read file: %v", err)
+		return subcommands.ExitFailure
+	}
+	for _, msg := range sc55.SplitSysEx(data) {
+		fmt.Println(describeMessage(msg))
+	}
+	return subcommands.ExitSuccess
+}
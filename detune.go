@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/fragglet/sc55ctl/sc55/notes"
+	"github.com/google/subcommands"
+)
+
+// detuneReference is the frequency at which detune values in cents are
+// exact. Pitch offset fine is an absolute offset in Hz rather than a ratio,
+// so the same setting is a smaller interval for higher notes.
+const detuneReference = notes.ConcertPitch
+
+// detunePresets are the amounts in cents that each part is shifted by in
+// -preset mode; see detuneSpread.
+var detunePresets = map[string]float64{
+	"off":    0,
+	"subtle": 3,
+	"chorus": 6,
+	"wide":   10,
+}
+
+// pitchOffsetFine returns a message setting the pitch offset fine register
+// of the given part to detune it by the given number of cents. The register
+// is in steps of 0.1Hz from -12Hz to +12Hz.
+func pitchOffsetFine(device sc55.DeviceID, p *sc55.Part, cents float64) ([]byte, error) {
+	hz := detuneReference * (math.Pow(2, cents/1200) - 1)
+	return p.PitchOffsetFine.Set(device, int(math.Round(hz*10)))
+}
+
+// detuneSpread returns the detune for each part for the given preset amount.
+// Parts alternate between sharp and flat so that pairs of parts playing in
+// unison beat against each other like a chorus; rhythm parts are left
+// alone.
+func detuneSpread(cents float64) map[int]float64 {
+	result := map[int]float64{}
+	sign := 1.0
+	for i := 1; i <= sc55.PartCount(); i++ {
+		if i%16 == 10 {
+			continue
+		}
+		result[i] = sign * cents
+		sign = -sign
+	}
+	return result
+}
+
+type detuneCommand struct {
+	preset     string
+	checkpoint bool
+}
+
+func (*detuneCommand) Name() string { return "detune" }
+func (*detuneCommand) Synopsis() string {
+	return "detune a part by a number of cents, or spread all parts with a preset"
+}
+func (*detuneCommand) Usage() string {
+	names := []string{}
+	for name := range detunePresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf(`detune <part> <cents> | detune -preset <name>:
+Sets pitch-offset-fine. Values are exact at %gHz; the available range is
+about +/-47 cents there. Presets: %s.
+`, detuneReference, strings.Join(names, ", "))
+}
+
+func (c *detuneCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.preset, "preset", "", "detune all parts alternately sharp and flat by a preset amount")
+	setCheckpointFlag(f, &c.checkpoint, false)
+}
+
+func (c *detuneCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	detune := map[int]float64{}
+	switch {
+	case c.preset != "" && len(f.Args()) == 0:
+		cents, ok := detunePresets[c.preset]
+		if !ok {
+			log.Printf("unknown preset %q", c.preset)
+			return subcommands.ExitUsageError
+		}
+		detune = detuneSpread(cents)
+	case c.preset == "" && len(f.Args()) == 2:
+		part, err := strconv.Atoi(f.Args()[0])
+		if err != nil || sc55.PartByNumber(part) == nil {
+			log.Printf("invalid part number %q", f.Args()[0])
+			return subcommands.ExitUsageError
+		}
+		cents, err := strconv.ParseFloat(f.Args()[1], 64)
+		if err != nil {
+			log.Printf("invalid number of cents: %v", err)
+			return subcommands.ExitUsageError
+		}
+		detune[part] = cents
+	default:
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	for part := 1; part <= sc55.PartCount(); part++ {
+		cents, ok := detune[part]
+		if !ok {
+			continue
+		}
+		msg, err := pitchOffsetFine(deviceID(), sc55.PartByNumber(part), cents)
+		if err == nil {
+			err = writeSysEx(out, msg)
+		}
+		if err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+		time.Sleep(bulkWriteDelay)
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// gifFrames returns each frame of an animated GIF as it is seen once drawn
+// over the frames before it, along with how long to show it for.
+func gifFrames(g *gif.GIF) ([]image.Image, []time.Duration) {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	canvas := image.NewRGBA(bounds)
+	frames := []image.Image{}
+	delays := []time.Duration{}
+	for i, frame := range g.Image {
+		var previous *image.RGBA
+		disposal := byte(0)
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			draw.Draw(previous, bounds, canvas, image.Point{}, draw.Src)
+		}
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		snapshot := image.NewRGBA(bounds)
+		draw.Draw(snapshot, bounds, canvas, image.Point{}, draw.Src)
+		frames = append(frames, snapshot)
+		delay := time.Duration(0)
+		if i < len(g.Delay) {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		delays = append(delays, delay)
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return frames, delays
+}
+
+// isGIF returns true if the file read by the given reader is a GIF.
+func isGIF(r *bufio.Reader) bool {
+	magic, err := r.Peek(4)
+	return err == nil && string(magic) == "GIF8"
+}
+
+type displayImageCommand struct {
+	repeat     int
+	checkpoint bool
+	fit        string
+	scaling    string
+}
+
+func (*displayImageCommand) Name() string { return "display-image" }
+func (*displayImageCommand) Synopsis() string {
+	return "Show a picture or animation on the SC-55 front panel"
+}
+func (*displayImageCommand) Usage() string {
+	return `display-image [flags] <file.png | file.gif>:
+Shows a 16x16 PNG or GIF image on the picture display. Images of other
+sizes are rejected unless -fit is given to scale them. Animated GIFs are
+played using the frame delays stored in the file, though frames are never
+sent closer together than the bulk write delay (see calibrate), and are
+repeated as many times as the GIF says unless -repeat is given.
+`
+}
+
+func (c *displayImageCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setCheckpointFlag(f, &c.checkpoint, false)
+	f.StringVar(&c.fit, "fit", "", "scale images that aren't 16x16 to fit the display: stretch, crop (fill the display, cutting off the edges) or letterbox (fit within the display, leaving blank bars)")
+	f.StringVar(&c.scaling, "scaling", string(sc55.ScaleNearest), "how to scale images with -fit: nearest (sharp, for pixel art) or bilinear (smooth, for photos)")
+	f.IntVar(&c.repeat, "repeat", -1, "number of times to play an animated GIF; 0 repeats forever, and if not given the GIF's own loop count is used")
+}
+
+// loadImage reads the frames of a PNG or GIF file, with how long to show
+// each for and the number of times to play them (0 for forever).
+func (c *displayImageCommand) loadImage(filename string) ([]image.Image, []time.Duration, int, error) {
+	in, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer in.Close()
+	r := bufio.NewReader(in)
+	if !isGIF(r) {
+		img, err := png.Decode(r)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		return []image.Image{img}, []time.Duration{0}, 1, nil
+	}
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	frames, delays := gifFrames(g)
+	// LoopCount counts restarts, with -1 meaning that the frames are
+	// only shown once.
+	count := g.LoopCount + 1
+	if g.LoopCount == 0 {
+		count = 0
+	}
+	if c.repeat >= 0 {
+		count = c.repeat
+	}
+	return frames, delays, count, nil
+}
+
+func (c *displayImageCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	frames, delays, count, err := c.loadImage(f.Args()[0])
+	if err != nil {
+		log.Printf("failed to load image: %v", err)
+		return subcommands.ExitFailure
+	}
+	msgs := [][]byte{}
+	for _, img := range frames {
+		if c.fit != "" {
+			if img, err = sc55.FitImage(img, sc55.Fit(c.fit), sc55.Scaling(c.scaling)); err != nil {
+				log.Printf("%v", err)
+				return subcommands.ExitUsageError
+			}
+		}
+		msg, err := sc55.DisplayImage(deviceID(), img)
+		if err != nil {
+			log.Printf("%v", err)
+			return subcommands.ExitUsageError
+		}
+		msgs = append(msgs, msg)
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	if len(msgs) == 1 {
+		count = 1
+	}
+	for i := 0; count == 0 || i < count; i++ {
+		for j, msg := range msgs {
+			start := time.Now()
+			if err := writeSysEx(out, msg); err != nil {
+				log.Printf("failed to write message to output: %v", err)
+				return subcommands.ExitFailure
+			}
+			if len(msgs) > 1 {
+				time.Sleep(time.Until(start.Add(max(delays[j], bulkWriteDelay))))
+			}
+		}
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type displayScrollCommand struct {
+	speed  float64
+	repeat int
+}
+
+func (*displayScrollCommand) Name() string { return "display-scroll" }
+func (*displayScrollCommand) Synopsis() string {
+	return "scroll a message in large letters across the SC-55 front panel picture display"
+}
+func (*displayScrollCommand) Usage() string {
+	return `display-scroll [flags] <message>:
+Draws the message in the same font as display-text and scrolls it from
+right to left across the picture display, one frame per dot. Frames are
+never sent closer together than the bulk write delay (see calibrate), so
+very high speeds are slowed down to what the device can take.
+`
+}
+
+func (c *displayScrollCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.Float64Var(&c.speed, "speed", 20, "scrolling speed, in dots per second")
+	f.IntVar(&c.repeat, "repeat", 1, "number of times to scroll the message; 0 repeats forever")
+}
+
+func (c *displayScrollCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) == 0 || c.speed <= 0 || c.repeat < 0 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	msgs, err := sc55.DisplayScroll(deviceID(), strings.Join(f.Args(), " "))
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	interval := time.Duration(float64(time.Second) / c.speed)
+	if interval < bulkWriteDelay {
+		log.Printf("-speed %g is too fast for the device; scrolling at %g dots per second", c.speed, float64(time.Second)/float64(bulkWriteDelay))
+		interval = bulkWriteDelay
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; c.repeat == 0 || i < c.repeat; i++ {
+		for _, msg := range msgs {
+			if err := writeSysEx(out, msg); err != nil {
+				log.Printf("failed to write message to output: %v", err)
+				return subcommands.ExitFailure
+			}
+			<-ticker.C
+		}
+	}
+	return subcommands.ExitSuccess
+}
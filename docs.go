@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// docsRegister is a register as shown on the documentation page.
+type docsRegister struct {
+	sc55.RegisterInfo
+	Range   string
+	Example string
+	SetMsg  string
+	GetMsg  string
+}
+
+// docsText is a text register as shown on the documentation page.
+type docsText struct {
+	Name, Address string
+	Size          int
+	GetMsg        string
+}
+
+// docsPage is the data used to generate the documentation page.
+type docsPage struct {
+	Version   string
+	Model     string
+	Registers []docsRegister
+	Text      []docsText
+}
+
+// hexBytes formats a message for display.
+func hexBytes(msg []byte) string {
+	return fmt.Sprintf("% x", msg)
+}
+
+// currentDocs returns the documentation for the registers on the current
+// model, with example messages for the device ID given by -sc55_device_id.
+func currentDocs() (docsPage, error) {
+	page := docsPage{
+		Version: versionString(),
+		Model:   sc55.CurrentModel().String(),
+	}
+	for _, r := range sc55.AllRegisters() {
+		min, max := r.Range()
+		example := sc55.Clamp(0, min, max)
+		msg, err := r.Set(deviceID(), example)
+		if err != nil {
+			return docsPage{}, err
+		}
+		page.Registers = append(page.Registers, docsRegister{
+			RegisterInfo: r.Info(),
+			Range:        r.FormatValue(min) + " .. " + r.FormatValue(max),
+			Example:      r.FormatValue(example),
+			SetMsg:       hexBytes(msg),
+			GetMsg:       hexBytes(r.Get(deviceID())),
+		})
+	}
+	for _, t := range sc55.AllTextRegisters() {
+		page.Text = append(page.Text, docsText{
+			Name:    t.Name(),
+			Address: fmt.Sprintf("%06x", t.Address),
+			Size:    t.Size,
+			GetMsg:  hexBytes(t.Get(deviceID())),
+		})
+	}
+	return page, nil
+}
+
+var docsTemplate = template.Must(template.New("docs").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>sc55ctl registers ({{.Model}})</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+th { background: #eee; position: sticky; top: 0; }
+code { font-size: 90%; white-space: nowrap; }
+.dim { color: #777; }
+</style>
+</head>
+<body>
+<h1>SoundCanvas registers: {{.Model}}</h1>
+<p class="dim">Generated by sc55ctl {{.Version}}. Values are as passed to
+<code>sc55ctl set</code>; example messages use the device ID given by
+-sc55_device_id. Also available as <a href="registers.json">JSON</a> and as
+a <a href="schema.json">settings file schema</a>.</p>
+<p><input id="filter" type="search" placeholder="Filter by name, group, tag or address" size="40" autofocus></p>
+<table id="registers">
+<tr><th>Name</th><th>Address</th><th>Size</th><th>Range</th><th>Units</th><th>Values</th><th>Tags</th><th>Model</th><th>Example</th></tr>
+{{range .Registers}}
+<tr data-search="{{.Name}} {{.Address}} {{.Group}} {{join .Tags " "}}">
+<td><code>{{.Name}}</code></td>
+<td><code>{{.Address}}</code></td>
+<td>{{.Size}}{{if eq .Encoding "nibbles"}} <span class="dim">(nibbles)</span>{{end}}</td>
+<td>{{.Range}}</td>
+<td>{{.Units}}</td>
+<td>{{range $i, $v := .Values}}{{if $i}}, {{end}}{{$i}}={{$v}}{{end}}</td>
+<td>{{join .Tags ", "}}</td>
+<td>{{.Model}}</td>
+<td>set to {{.Example}}: <code>{{.SetMsg}}</code><br>
+read: <code>{{.GetMsg}}</code>{{if .Controller}}<br>
+or control change {{.Controller}} on the part's channel{{end}}</td>
+</tr>
+{{end}}
+</table>
+<h2>Text registers</h2>
+<table>
+<tr><th>Name</th><th>Address</th><th>Length</th><th>Read</th></tr>
+{{range .Text}}
+<tr><td><code>{{.Name}}</code></td><td><code>{{.Address}}</code></td><td>{{.Size}}</td><td><code>{{.GetMsg}}</code></td></tr>
+{{end}}
+</table>
+<script>
+document.getElementById("filter").addEventListener("input", function(e) {
+	var words = e.target.value.toLowerCase().split(/\s+/);
+	document.querySelectorAll("#registers tr[data-search]").forEach(function(row) {
+		var text = row.dataset.search.toLowerCase();
+		row.hidden = !words.every(function(w) { return text.indexOf(w) >= 0; });
+	});
+});
+</script>
+</body>
+</html>
+`))
+
+// docsHTML returns the documentation page.
+func docsHTML() ([]byte, error) {
+	page, err := currentDocs()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := docsTemplate.Execute(&buf, page); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// serveJSON returns a handler that serves the given value as JSON.
+func serveJSON(value func() interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.MarshalIndent(value(), "", "\t")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+type docsCommand struct {
+	addr string
+}
+
+func (*docsCommand) Name() string { return "docs" }
+func (*docsCommand) Synopsis() string {
+	return "browse the register map as a web page"
+}
+func (*docsCommand) Usage() string {
+	return `docs serve | docs html <out.html>:
+Generates a page listing every register on the model chosen with -model,
+with its address, range, values and example SysEx messages. "serve" runs
+a local web server showing the page (at http://localhost:5555/ unless
+-addr is given); "html" writes it to a file instead.
+`
+}
+
+func (c *docsCommand) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&sc55DeviceID, "sc55_device_id", int(sc55.DefaultDevice), "device ID to use in the example messages")
+	f.StringVar(&c.addr, "addr", "localhost:5555", "address for the web server to listen on")
+}
+
+func (c *docsCommand) serve() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		data, err := docsHTML()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(data)
+	})
+	mux.HandleFunc("/registers.json", serveJSON(func() interface{} { return sc55.Catalog() }))
+	mux.HandleFunc("/schema.json", serveJSON(func() interface{} { return settingsSchema() }))
+	log.Printf("serving register documentation at http://%s/", c.addr)
+	return http.ListenAndServe(c.addr, mux)
+}
+
+func (c *docsCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	args := f.Args()
+	switch {
+	case len(args) == 1 && args[0] == "serve":
+		if err := c.serve(); err != nil {
+			log.Printf("web server failed: %v", err)
+			return subcommands.ExitFailure
+		}
+	case len(args) == 2 && args[0] == "html":
+		data, err := docsHTML()
+		if err != nil {
+			log.Printf("failed to generate page: %v", err)
+			return subcommands.ExitFailure
+		}
+		if err := os.WriteFile(args[1], data, 0644); err != nil {
+			log.Printf("failed to write page: %v", err)
+			return subcommands.ExitFailure
+		}
+	default:
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type drumMapCommand struct {
+	checkpoint bool
+}
+
+func (*drumMapCommand) Name() string { return "drum-map" }
+func (*drumMapCommand) Synopsis() string {
+	return "show the drum maps and which parts use them, or choose a part's drum map"
+}
+func (*drumMapCommand) Usage() string {
+	return `drum-map [<part> <map>]:
+With no arguments, prints the name of each drum map and the parts playing
+it. Otherwise sets the given part to play drum map 1 or 2, or to be a
+normal part if map is 0.
+`
+}
+
+func (c *drumMapCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setCheckpointFlag(f, &c.checkpoint, false)
+}
+
+// show prints the drum map names and the parts using each map.
+func (c *drumMapCommand) show() subcommands.ExitStatus {
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	regs := []*sc55.Register{}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		regs = append(regs, &sc55.PartByNumber(i).UseForRhythm)
+	}
+	values, errs := queryRegisters(in, out, deviceID(), regs, replyTimeout)
+	for m := 1; m <= sc55.NumDrumMaps; m++ {
+		t := sc55.DrumMapName(m)
+		name := "?"
+		if data, err := queryBlock(in, out, deviceID(), t.Block(), replyTimeout); err == nil {
+			if value, err := t.Decode(data); err == nil {
+				name = fmt.Sprintf("%q", value)
+			}
+		}
+		users := []int{}
+		for i, r := range regs {
+			if errs[r] == nil && values[r] == m {
+				users = append(users, i+1)
+			}
+		}
+		fmt.Printf("drum map %d  %-14s  parts: %v\n", m, name, users)
+	}
+	for _, r := range regs {
+		if err := errs[r]; err != nil {
+			log.Printf("error querying register %q: %v", r.Name(), err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
+
+func (c *drumMapCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	switch len(f.Args()) {
+	case 0:
+		return c.show()
+	case 2:
+	default:
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	part, err := strconv.Atoi(f.Args()[0])
+	if err != nil || sc55.PartByNumber(part) == nil {
+		log.Printf("invalid part number %q", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	drumMap, err := strconv.Atoi(f.Args()[1])
+	if err != nil {
+		log.Printf("invalid drum map %q", f.Args()[1])
+		return subcommands.ExitUsageError
+	}
+	msg, err := sc55.PartByNumber(part).SetDrumMap(deviceID(), drumMap)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	if err := writeSysEx(out, msg); err != nil {
+		log.Printf("failed to write message to output: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rakyll/portmidi"
+)
+
+const (
+	statusClock = 0xf8
+	statusStart = 0xfa
+
+	// clocksPerWholeNote is the number of MIDI clock messages in a whole
+	// note (24 per quarter note).
+	clocksPerWholeNote = 96
+)
+
+// eventQueue holds events to be sent later, in timestamp order.
+type eventQueue []portmidi.Event
+
+func (q *eventQueue) push(e portmidi.Event) {
+	i := sort.Search(len(*q), func(i int) bool { return (*q)[i].Timestamp > e.Timestamp })
+	*q = append(*q, portmidi.Event{})
+	copy((*q)[i+1:], (*q)[i:])
+	(*q)[i] = e
+}
+
+// popDue removes and returns the events that are due to be sent by now.
+func (q *eventQueue) popDue(now portmidi.Timestamp) []portmidi.Event {
+	n := sort.Search(len(*q), func(i int) bool { return (*q)[i].Timestamp > now })
+	due := append([]portmidi.Event{}, (*q)[:n]...)
+	*q = (*q)[n:]
+	return due
+}
+
+func isNoteOn(e portmidi.Event) bool {
+	return isChannelMessage(e) && e.Status&0xf0 == statusNoteOn && e.Data2 > 0
+}
+
+func isNoteOff(e portmidi.Event) bool {
+	status := e.Status & 0xf0
+	return isChannelMessage(e) && (status == statusNoteOff || (status == statusNoteOn && e.Data2 == 0))
+}
+
+// echoTransform repeats notes after a delay, each repeat quieter than the
+// one before by the feedback factor.
+type echoTransform struct {
+	DelayMS  int64      `json:"delay_ms"`
+	Repeats  int        `json:"repeats"`
+	Feedback float64    `json:"feedback"`
+	Channels channelSet `json:"channels"`
+}
+
+func (t *echoTransform) apply(e portmidi.Event) []portmidi.Event {
+	result := []portmidi.Event{e}
+	if !(isNoteOn(e) || isNoteOff(e)) || !t.Channels.matches(e) {
+		return result
+	}
+	for k := 1; k <= t.Repeats; k++ {
+		echo := e
+		echo.Timestamp += portmidi.Timestamp(int64(k) * t.DelayMS)
+		if isNoteOn(e) {
+			v := math.Round(float64(e.Data2) * math.Pow(t.Feedback, float64(k)))
+			// Every echo must stay a note on, so that it matches up
+			// with the echo of the note off.
+			echo.Data2 = int64(math.Max(1, v))
+		}
+		result = append(result, echo)
+	}
+	return result
+}
+
+// arpeggio is the state of the arpeggiator for one channel.
+type arpeggio struct {
+	// notes are the held notes, in pitch order, with their velocities.
+	notes    []portmidi.Event
+	pos, dir int
+}
+
+// arpeggiatorTransform plays the held notes on each channel one at a time,
+// either at its own tempo or in time with incoming MIDI clock messages.
+type arpeggiatorTransform struct {
+	BPM float64 `json:"bpm"`
+	// Division is the number of steps in a whole note, eg. 16 for
+	// sixteenth notes.
+	Division int `json:"division"`
+	// Mode is "up", "down" or "up-down".
+	Mode string `json:"mode"`
+	// Gate is the length of each note as a fraction of a step.
+	Gate float64 `json:"gate"`
+	// Clock syncs the steps to MIDI clock messages instead of BPM.
+	Clock    bool       `json:"clock"`
+	Channels channelSet `json:"channels"`
+
+	arpeggios map[int64]*arpeggio
+	nextStep  portmidi.Timestamp
+	clocks    int
+	lastClock portmidi.Timestamp
+}
+
+func newArpeggiator() *arpeggiatorTransform {
+	return &arpeggiatorTransform{
+		BPM:       120,
+		Division:  16,
+		Mode:      "up",
+		Gate:      0.5,
+		arpeggios: map[int64]*arpeggio{},
+	}
+}
+
+func (t *arpeggiatorTransform) UnmarshalJSON(data []byte) error {
+	type plainArpeggiator arpeggiatorTransform
+	if err := json.Unmarshal(data, (*plainArpeggiator)(t)); err != nil {
+		return err
+	}
+	switch {
+	case t.Mode != "up" && t.Mode != "down" && t.Mode != "up-down":
+		return fmt.Errorf("unknown arpeggiator mode %q: want up, down or up-down", t.Mode)
+	case t.Division < 1 || t.Division > clocksPerWholeNote:
+		return fmt.Errorf("invalid arpeggiator division %d, want 1 <= x <= %d", t.Division, clocksPerWholeNote)
+	case t.BPM <= 0:
+		return fmt.Errorf("invalid arpeggiator bpm %v", t.BPM)
+	case t.Gate <= 0 || t.Gate > 1:
+		return fmt.Errorf("invalid arpeggiator gate %v, want 0 < x <= 1", t.Gate)
+	}
+	return nil
+}
+
+// stepMS returns the length of a step at the internal tempo.
+func (t *arpeggiatorTransform) stepMS() float64 {
+	return 60000 / t.BPM * 4 / float64(t.Division)
+}
+
+// hold adds or removes a note from the held notes.
+func (t *arpeggiatorTransform) hold(e portmidi.Event) {
+	channel := e.Status & 0x0f
+	a, ok := t.arpeggios[channel]
+	if !ok {
+		a = &arpeggio{dir: 1}
+		t.arpeggios[channel] = a
+	}
+	notes := a.notes[:0]
+	for _, n := range a.notes {
+		if n.Data1 != e.Data1 {
+			notes = append(notes, n)
+		}
+	}
+	if isNoteOn(e) {
+		notes = append(notes, e)
+		sort.Slice(notes, func(i, j int) bool { return notes[i].Data1 < notes[j].Data1 })
+	}
+	a.notes = notes
+}
+
+// next returns the index of the note to play on the next step.
+func (a *arpeggio) next(mode string) int {
+	n := len(a.notes)
+	if a.pos >= n {
+		a.pos = 0
+	}
+	i := a.pos
+	switch mode {
+	case "down":
+		i = n - 1 - a.pos
+		a.pos = (a.pos + 1) % n
+	case "up-down":
+		if n > 1 && (a.pos+a.dir < 0 || a.pos+a.dir >= n) {
+			a.dir = -a.dir
+		}
+		if n > 1 {
+			a.pos += a.dir
+		}
+	default:
+		a.pos = (a.pos + 1) % n
+	}
+	return i
+}
+
+// step plays the next note on every channel with held notes.
+func (t *arpeggiatorTransform) step(now portmidi.Timestamp, length float64) []portmidi.Event {
+	result := []portmidi.Event{}
+	for _, a := range t.arpeggios {
+		if len(a.notes) == 0 {
+			continue
+		}
+		on := a.notes[a.next(t.Mode)]
+		on.Timestamp = now
+		off := on
+		off.Status = statusNoteOff | on.Status&0x0f
+		off.Data2 = 0
+		off.Timestamp = now + portmidi.Timestamp(math.Max(1, length*t.Gate))
+		result = append(result, on, off)
+	}
+	return result
+}
+
+func (t *arpeggiatorTransform) apply(e portmidi.Event) []portmidi.Event {
+	switch {
+	case t.Clock && e.SysEx == nil && e.Status == statusStart:
+		t.clocks = 0
+	case t.Clock && e.SysEx == nil && e.Status == statusClock:
+		clocksPerStep := clocksPerWholeNote / t.Division
+		// The step length is estimated from the time since the last
+		// clock, falling back to the BPM for the first one.
+		length := t.stepMS()
+		if t.clocks > 0 {
+			length = float64((e.Timestamp - t.lastClock) * portmidi.Timestamp(clocksPerStep))
+		}
+		t.clocks++
+		t.lastClock = e.Timestamp
+		if t.clocks%clocksPerStep == 0 {
+			return append([]portmidi.Event{e}, t.step(e.Timestamp, length)...)
+		}
+	case (isNoteOn(e) || isNoteOff(e)) && t.Channels.matches(e):
+		t.hold(e)
+		return nil
+	}
+	return []portmidi.Event{e}
+}
+
+func (t *arpeggiatorTransform) tick(now portmidi.Timestamp) []portmidi.Event {
+	if t.Clock || now < t.nextStep {
+		return nil
+	}
+	step := t.stepMS()
+	t.nextStep += portmidi.Timestamp(step)
+	if t.nextStep <= now {
+		// Fallen behind, or nothing has been played yet.
+		t.nextStep = now + portmidi.Timestamp(step)
+	}
+	return t.step(now, step)
+}
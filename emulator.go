@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/rakyll/portmidi"
+)
+
+// emulatorPortPatterns match the MIDI port names used by common software
+// emulators. Port naming differs between platforms and MIDI backends (ALSA,
+// CoreMIDI, WinMM) so each pattern allows for the variants seen. Generic
+// names such as "SC-55" or "Sound Canvas" are deliberately not matched,
+// since the USB MIDI ports of real Roland hardware use them too. Matching
+// is case-insensitive; earlier entries take priority.
+var emulatorPortPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^mt-32 synth emulator\b`), // Munt mt32emu-qt (all platforms)
+	regexp.MustCompile(`(?i)\bmt32emu\b`),
+	regexp.MustCompile(`(?i)\bmunt\b`),
+	regexp.MustCompile(`(?i)\bnuked[- ]?sc-?55\b`),
+}
+
+// emulatorPort returns the ID of the first port whose name matches one of
+// the known emulator port names.
+func emulatorPort(output bool) (portmidi.DeviceID, error) {
+	for _, pattern := range emulatorPortPatterns {
+		for i := 0; i < portmidi.CountDevices(); i++ {
+			id := portmidi.DeviceID(i)
+			info := portmidi.Info(id)
+			switch {
+			case output && !info.IsOutputAvailable:
+				continue
+			case !output && !info.IsInputAvailable:
+				continue
+			}
+			if pattern.MatchString(info.Name) {
+				return id, nil
+			}
+		}
+	}
+	return portmidi.DeviceID(-1), fmt.Errorf("no emulator MIDI port found; known names: %q", emulatorPortPatterns)
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image/png"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// cArray is a named sequence of bytes to write to a C header.
+type cArray struct {
+	name    string
+	comment string
+	data    []byte
+}
+
+// validCIdentifier matches the identifiers that can be used as a prefix.
+var validCIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// cHeader returns the text of a C header file declaring each of the
+// given arrays, along with a macro giving its length. Only C89 features are
+// used so that the result can be compiled by DOS-era compilers.
+func cHeader(prefix string, arrays []cArray) []byte {
+	var buf bytes.Buffer
+	guard := strings.ToUpper(prefix) + "_SYSEX_H"
+	fmt.Fprintf(&buf, "/* Generated by sc55ctl export-c. */\n\n")
+	fmt.Fprintf(&buf, "#ifndef %s\n#define %s\n", guard, guard)
+	for _, a := range arrays {
+		name := prefix + "_" + a.name
+		fmt.Fprintf(&buf, "\n/* %s */\n", strings.ReplaceAll(a.comment, "*/", "* /"))
+		fmt.Fprintf(&buf, "#define %s_LEN %d\n", strings.ToUpper(name), len(a.data))
+		fmt.Fprintf(&buf, "static const unsigned char %s[%s_LEN] = {", name, strings.ToUpper(name))
+		for i, b := range a.data {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if i%12 == 0 {
+				buf.WriteString("\n   ")
+			}
+			fmt.Fprintf(&buf, " 0x%02x", b)
+		}
+		buf.WriteString("\n};\n")
+	}
+	fmt.Fprintf(&buf, "\n#endif /* %s */\n", guard)
+	return buf.Bytes()
+}
+
+type exportCCommand struct {
+	prefix       string
+	reset        bool
+	message      string
+	image        string
+	setup        bool
+	settingsFile string
+	all          bool
+}
+
+func (*exportCCommand) Name() string { return "export-c" }
+func (*exportCCommand) Synopsis() string {
+	return "write SysEx messages as C arrays, for including in other programs"
+}
+func (*exportCCommand) Usage() string {
+	return `export-c [flags] <out.h>:
+Writes a C header containing the selected messages as byte arrays, each
+with a _LEN macro giving its length, for use in programs (eg. DOS games
+and players) that drive a SoundCanvas directly. The setup array contains
+one SysEx message per register, one after another; the device needs a
+short delay between each.
+`
+}
+
+func (c *exportCCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.prefix, "prefix", "sc55", "prefix for the names of the arrays and macros")
+	f.BoolVar(&c.reset, "reset", true, "include a GS reset message")
+	f.StringVar(&c.message, "message", "", "include a message showing the given text on the display")
+	f.StringVar(&c.image, "image", "", "include a message showing the given PNG image on the display")
+	f.BoolVar(&c.setup, "setup", false, "include the messages to apply the settings from -settings or the device")
+	f.StringVar(&c.settingsFile, "settings", "", "settings file to export with -setup; if not given, the current device state is read")
+	f.BoolVar(&c.all, "all", false, "when reading from the device, export all registers rather than just the important ones")
+}
+
+// arrays returns the messages selected by the flags.
+func (c *exportCCommand) arrays() ([]cArray, error) {
+	result := []cArray{}
+	if c.reset {
+		result = append(result, cArray{"gs_reset", "GS reset", sc55.ResetGS(deviceID())})
+	}
+	if c.message != "" {
+		result = append(result, cArray{"display_message", fmt.Sprintf("Display message %q", c.message), sc55.DisplayMessage(deviceID(), c.message)})
+	}
+	if c.image != "" {
+		in, err := os.Open(c.image)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Close()
+		img, err := png.Decode(in)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := sc55.DisplayImage(deviceID(), img)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cArray{"display_image", "Display image", msg})
+	}
+	if c.setup {
+		s, err := currentSettings(c.settingsFile, c.all)
+		if err != nil {
+			return nil, err
+		}
+		regs, unknown := s.registers()
+		for _, name := range unknown {
+			log.Printf("ignoring unknown register %q", name)
+		}
+		data := []byte{}
+		for _, r := range regs {
+			msg, err := setRegister(r, deviceID(), s[r.Name()])
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, msg...)
+		}
+		result = append(result, cArray{"setup", fmt.Sprintf("Setup: %d registers", len(regs)), data})
+	}
+	return result, nil
+}
+
+func (c *exportCCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("output filename not provided")
+		return subcommands.ExitUsageError
+	}
+	if !validCIdentifier.MatchString(c.prefix) {
+		log.Printf("invalid prefix %q: must be a valid C identifier", c.prefix)
+		return subcommands.ExitUsageError
+	}
+	arrays, err := c.arrays()
+	if err != nil {
+		log.Printf("failed to generate messages: %v", err)
+		return subcommands.ExitFailure
+	}
+	if len(arrays) == 0 {
+		log.Printf("no messages selected")
+		return subcommands.ExitUsageError
+	}
+	if err := os.WriteFile(f.Args()[0], cHeader(c.prefix, arrays), 0644); err != nil {
+		log.Printf("failed to write header: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
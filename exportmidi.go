@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// gsResetDelay is how long to wait after a GS reset before sending anything
+// else, since the SoundCanvas ignores messages while it reinitializes.
+const gsResetDelay = 50 * time.Millisecond
+
+type exportSetupMIDICommand struct {
+	settingsFile string
+	all          bool
+	reset        bool
+}
+
+func (*exportSetupMIDICommand) Name() string { return "export-setup-midi" }
+func (*exportSetupMIDICommand) Synopsis() string {
+	return "write settings as a type 0 MIDI file that configures the SoundCanvas when played"
+}
+func (*exportSetupMIDICommand) Usage() string { return "export-setup-midi [flags] <out.mid>:\n" }
+
+func (c *exportSetupMIDICommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.settingsFile, "settings", "", "settings file to export; if not given, the current device state is read")
+	f.BoolVar(&c.all, "all", false, "when reading from the device, export all registers rather than just the important ones")
+	f.BoolVar(&c.reset, "reset", true, "start the file with a GS reset")
+}
+
+// setupTrack returns a MIDI track containing the SysEx messages needed to
+// apply the given settings, spaced out so the device can keep up.
+func setupTrack(s settings, device sc55.DeviceID, reset bool) (*smfTrack, error) {
+	t := &smfTrack{}
+	t.addTempo(0, smfTempo)
+	when := time.Duration(0)
+	if reset {
+		t.addSysEx(0, sc55.ResetGS(device))
+		when += gsResetDelay
+	}
+	regs, unknown := s.registers()
+	for _, name := range unknown {
+		log.Printf("ignoring unknown register %q", name)
+	}
+	for _, r := range regs {
+		msg, err := setRegister(r, device, s[r.Name()])
+		if err != nil {
+			return nil, err
+		}
+		t.addSysEx(smfTicks(when), msg)
+		when += bulkWriteDelay
+	}
+	return t, nil
+}
+
+func (c *exportSetupMIDICommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("output filename not provided")
+		return subcommands.ExitUsageError
+	}
+	s, err := currentSettings(c.settingsFile, c.all)
+	if err != nil {
+		log.Printf("failed to get settings: %v", err)
+		return subcommands.ExitFailure
+	}
+	t, err := setupTrack(s, deviceID(), c.reset)
+	if err != nil {
+		log.Printf("failed to generate setup: %v", err)
+		return subcommands.ExitFailure
+	}
+	if err := writeSMF(f.Args()[0], t); err != nil {
+		log.Printf("failed to write MIDI file: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
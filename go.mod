@@ -6,3 +6,5 @@ require (
 	github.com/google/subcommands v1.2.0
 	github.com/rakyll/portmidi v0.0.0-20201020180702-d436ceaa537a
 )
+
+replace github.com/rakyll/portmidi => ./third_party/portmidi
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+type identifyCommand struct {
+	timeout time.Duration
+	probe   bool
+	save    bool
+}
+
+func (*identifyCommand) Name() string { return "identify" }
+func (*identifyCommand) Synopsis() string {
+	return "ask the connected module what model it is and print its firmware version"
+}
+func (*identifyCommand) Usage() string {
+	return `identify [flags]:
+Sends a universal identity request and prints the reply. Unless -probe is
+turned off, the model is then worked out by reading a register that each
+model added, newest first (modules ignore requests for registers they
+don't have), and the -model flag to use with it is shown. This tells the
+SC-55 and SC-55mkII apart, which may not reply to identity requests.
+Products based on the same model, such as the CM-300, can't be told apart
+from it this way, so they are listed too.
+
+If -model wasn't given, the profile for the model found is saved in the
+config file and used by later commands that aren't given -model either,
+unless -save is turned off. A product profile (eg. -model cm300) that is
+already saved is kept if it is based on the model found.
+`
+}
+
+func (c *identifyCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.DurationVar(&c.timeout, "timeout", 500*time.Millisecond, "how long to wait for a reply from the SoundCanvas before timing out")
+	f.BoolVar(&c.probe, "probe", true, "work out the model by reading registers that only later models have")
+	f.BoolVar(&c.save, "save", true, "if -model wasn't given, save the profile for the model found in the config file")
+}
+
+// saveProfile makes the profile for the given model the default in the
+// config file, unless the current profile is already based on it.
+func saveProfile(m sc55.Model) error {
+	p := sc55.CurrentProfile()
+	if p.Model != m {
+		p, _ = sc55.ProfileByName(m.String())
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Model = p.Name
+	if err := cfg.save(); err != nil {
+		return err
+	}
+	sc55.SetProfile(p)
+	log.Printf("using -model %s (%s) from now on; saved to %s", p.Name, p.Description, configFilename())
+	return nil
+}
+
+// probeModel works out the model of the device by reading the register
+// that each model added, newest first; the first to reply is the model.
+func probeModel(in, out *portmidi.Stream, device sc55.DeviceID, timeout time.Duration) (sc55.Model, error) {
+	models := sc55.AllModels()
+	for i := len(models) - 1; i >= 0; i-- {
+		r := models[i].ProbeRegister()
+		if r == nil {
+			continue
+		}
+		if _, err := queryRegister(in, out, device, r, timeout); err == nil {
+			return models[i], nil
+		}
+	}
+	return sc55.ModelSC55, fmt.Errorf("no reply to any request")
+}
+
+// readIdentity sends an identity request and waits for the reply,
+// returning false if none arrives before the timeout.
+func readIdentity(in, out *portmidi.Stream, device sc55.DeviceID, timeout time.Duration) (sc55.Identity, bool, error) {
+	if err := writeSysEx(out, sc55.IdentityRequest(device)); err != nil {
+		return sc55.Identity{}, false, fmt.Errorf("failed to write message to output: %v", err)
+	}
+	timeoutTime := time.Now().Add(timeout)
+	for time.Now().Before(timeoutTime) {
+		reply, err := readSysEx(in)
+		if err != nil {
+			return sc55.Identity{}, false, fmt.Errorf("failed to read reply: %v", err)
+		}
+		if len(reply) == 0 {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		id, err := sc55.UnmarshalIdentity(reply)
+		if err != nil || !id.Device.Matches(device) {
+			continue
+		}
+		return id, true, nil
+	}
+	return sc55.Identity{}, false, nil
+}
+
+func (c *identifyCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	id, ok, err := readIdentity(in, out, deviceID(), c.timeout)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitFailure
+	}
+	if ok {
+		fmt.Printf("device %02x: %v\n", id.Device, id)
+	} else {
+		log.Printf("no identity reply received; older modules such as the original SC-55 may not support identity requests")
+	}
+	if !c.probe {
+		if !ok {
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitSuccess
+	}
+	m, err := probeModel(in, out, deviceID(), c.timeout)
+	if err != nil {
+		log.Printf("failed to work out model: %v", err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("model: %s (use -model %s)\n", m, m)
+	for _, p := range sc55.AllProfiles() {
+		if p.Model == m && p.Name != m.String() {
+			fmt.Printf("  or -model %s for a %s\n", p.Name, p.Description)
+		}
+	}
+	switch {
+	case modelGiven:
+		if p := sc55.CurrentProfile(); p.Model != m {
+			log.Printf("warning: -model %s was given, but the device is a %s", p.Name, m)
+		}
+	case c.save:
+		if err := saveProfile(m); err != nil {
+			log.Printf("failed to save model: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+type setInstrumentCommand struct {
+	list bool
+}
+
+func (*setInstrumentCommand) Name() string { return "set-instrument" }
+func (*setInstrumentCommand) Synopsis() string {
+	return "select an instrument on a MIDI channel by name or bank and program number"
+}
+func (*setInstrumentCommand) Usage() string {
+	return `set-instrument <channel> <name> | set-instrument <channel> <bank> <program>:
+Sends a bank select and program change. Names are matched ignoring case
+and punctuation; use -list to show the built-in names. Programs are
+numbered 1-128 as in the manual.
+`
+}
+
+func (c *setInstrumentCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.BoolVar(&c.list, "list", false, "list the built-in instrument names")
+}
+
+// parseTone interprets the arguments after the channel number as either two
+// numbers (bank and program) or an instrument name.
+func parseTone(args []string) (sc55.Tone, error) {
+	if len(args) == 2 {
+		bank, err1 := strconv.Atoi(args[0])
+		program, err2 := strconv.Atoi(args[1])
+		if err1 == nil && err2 == nil {
+			return sc55.Tone{Bank: bank, Program: program}, nil
+		}
+	}
+	name := strings.Join(args, " ")
+	t, ok := sc55.ToneByName(name)
+	if !ok {
+		return sc55.Tone{}, fmt.Errorf("unknown instrument %q", name)
+	}
+	return t, nil
+}
+
+func (c *setInstrumentCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if c.list {
+		for _, t := range sc55.Tones() {
+			fmt.Printf("%3d  %3d  %s\n", t.Bank, t.Program, t.Name)
+		}
+		return subcommands.ExitSuccess
+	}
+	if len(f.Args()) < 2 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	channel, err := strconv.Atoi(f.Args()[0])
+	if err != nil {
+		log.Printf("invalid channel %q", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	t, err := parseTone(f.Args()[1:])
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	msgs, err := sc55.SelectTone(channel, t)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	for _, msg := range msgs {
+		e := portmidi.Event{Status: int64(msg[0]), Data1: int64(msg[1])}
+		if len(msg) > 2 {
+			e.Data2 = int64(msg[2])
+		}
+		if err := writeEvent(out, e); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/fragglet/sc55ctl/sc55/notes"
+	"github.com/google/subcommands"
+)
+
+// parseKeyRange parses a range of notes written as low..high, eg. C2..B4.
+func parseKeyRange(s string) (int, int, error) {
+	lowName, highName, ok := strings.Cut(s, "..")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid key range %q: want low..high, eg. C2..B4", s)
+	}
+	low, err := notes.NameToNumber(lowName)
+	if err != nil {
+		return 0, 0, err
+	}
+	high, err := notes.NameToNumber(highName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if low > high {
+		return 0, 0, fmt.Errorf("invalid key range %q: %s is above %s", s, notes.NumberToName(low), notes.NumberToName(high))
+	}
+	return low, high, nil
+}
+
+// overlappingParts returns the parts other than the given one that receive
+// on the same channel and whose key range overlaps low..high, so that they
+// would be layered with it.
+func overlappingParts(s settings, part, low, high int) []int {
+	p := sc55.PartByNumber(part)
+	channel := s[p.RxChannel.Name()]
+	result := []int{}
+	if channel >= 0x10 {
+		return result
+	}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		other := sc55.PartByNumber(i)
+		if i == part || s[other.RxChannel.Name()] != channel {
+			continue
+		}
+		if s[other.KeyRangeLow.Name()] <= high && s[other.KeyRangeHigh.Name()] >= low {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+type keyRangeCommand struct {
+	allowOverlap bool
+	checkpoint   bool
+}
+
+func (*keyRangeCommand) Name() string { return "key-range" }
+func (*keyRangeCommand) Synopsis() string {
+	return "set the range of notes that a part plays, using note names"
+}
+func (*keyRangeCommand) Usage() string {
+	return `key-range <part> <low>..<high>:
+Sets key-range-low and key-range-high for the given part. Notes are given
+by name with C4 as middle C (eg. C2..B4, F#3..G9), or as note numbers. If
+another part on the same receive channel already plays any of the notes,
+the change is refused unless -allow_overlap is given, since the parts
+would be layered.
+`
+}
+
+func (c *keyRangeCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.BoolVar(&c.allowOverlap, "allow_overlap", false, "set the range even if it layers the part with another part on the same channel")
+	setCheckpointFlag(f, &c.checkpoint, false)
+}
+
+func (c *keyRangeCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 2 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	part, err := strconv.Atoi(f.Args()[0])
+	if err != nil || sc55.PartByNumber(part) == nil {
+		log.Printf("invalid part number %q", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	low, high, err := parseKeyRange(f.Args()[1])
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	regs := []*sc55.Register{}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		p := sc55.PartByNumber(i)
+		regs = append(regs, &p.RxChannel, &p.KeyRangeLow, &p.KeyRangeHigh)
+	}
+	current, _, err := fetchSettings(in, out, regs, replyTimeout)
+	if err != nil {
+		log.Printf("failed to read parts: %v", err)
+		return subcommands.ExitFailure
+	}
+	if overlaps := overlappingParts(current, part, low, high); len(overlaps) > 0 && !c.allowOverlap {
+		for _, i := range overlaps {
+			p := sc55.PartByNumber(i)
+			log.Printf("part %d on the same channel already plays %s..%s", i,
+				notes.NumberToName(current[p.KeyRangeLow.Name()]), notes.NumberToName(current[p.KeyRangeHigh.Name()]))
+		}
+		log.Printf("refusing to layer part %d with other parts; use -allow_overlap to do it anyway", part)
+		return subcommands.ExitFailure
+	}
+	p := sc55.PartByNumber(part)
+	s := settings{
+		p.KeyRangeLow.Name():  low,
+		p.KeyRangeHigh.Name(): high,
+	}
+	if failures := s.apply(nil, out, deviceID()); len(failures) > 0 {
+		failures.log()
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/rakyll/portmidi"
+)
+
+// Error codes shown on the SoundCanvas display by -lcd_errors. Rack setups
+// often have no screen attached, so the front panel is the only place a
+// fatal error can be seen.
+const (
+	errCodeWrite  = 1 // writing to a MIDI output failed
+	errCodeDevice = 2 // a MIDI device could not be opened
+	errCodePanic  = 3 // internal error
+	errCodeRead   = 4 // reading from a MIDI input failed
+)
+
+var lcdErrors bool
+
+func setDisplayErrorFlags(f *flag.FlagSet) {
+	f.BoolVar(&lcdErrors, "lcd_errors", false, "show fatal errors on the SoundCanvas display before exiting; used by mirror, follow-volume, proxy, record-midi, transpose -live and apply -watch")
+}
+
+// displayError shows the given error code on the SoundCanvas front panel if
+// -lcd_errors is set. It is best effort: if the output stream is what
+// failed, the message is unlikely to get through.
+func displayError(out *portmidi.Stream, code int) {
+	if !lcdErrors || out == nil || !sc55.CurrentProfile().Display {
+		return
+	}
+	msg := sc55.DisplayMessage(deviceID(), fmt.Sprintf("SC55CTL ERR %d", code))
+	writeSysEx(out, msg)
+}
+
+// displayPanics should be deferred by long-running commands so that a panic
+// is shown on the display before the program crashes.
+func displayPanics(out *portmidi.Stream) {
+	if r := recover(); r != nil {
+		displayError(out, errCodePanic)
+		panic(r)
+	}
+}
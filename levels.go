@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"math"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// scaleLevels returns part-level settings scaled proportionally so that the
+// loudest part is at the target level.
+func scaleLevels(current settings, target int) settings {
+	max := 0
+	for _, value := range current {
+		if value > max {
+			max = value
+		}
+	}
+	result := settings{}
+	if max == 0 {
+		return result
+	}
+	for name, value := range current {
+		result[name] = int(math.Round(float64(value) * float64(target) / float64(max)))
+	}
+	return result
+}
+
+type normalizeLevelsCommand struct {
+	target     int
+	checkpoint bool
+}
+
+func (*normalizeLevelsCommand) Name() string { return "normalize-levels" }
+func (*normalizeLevelsCommand) Synopsis() string {
+	return "scale all part levels so that the loudest is at a target, keeping the balance between them"
+}
+func (*normalizeLevelsCommand) Usage() string { return "" }
+
+func (c *normalizeLevelsCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.IntVar(&c.target, "target", 100, "part level (0-127) to bring the loudest part to")
+	setCheckpointFlag(f, &c.checkpoint, true)
+}
+
+func (c *normalizeLevelsCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	if c.target < 0 || c.target > 127 {
+		log.Printf("invalid target level %d: must be 0-127", c.target)
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint (use -checkpoint=false to go ahead without one): %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	regs := []*sc55.Register{}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		regs = append(regs, &sc55.PartByNumber(i).PartLevel)
+	}
+	current, _, err := fetchSettings(in, out, regs, replyTimeout)
+	if err != nil {
+		log.Printf("failed to read part levels: %v", err)
+		return subcommands.ExitFailure
+	}
+	changed := changedSettings(current, scaleLevels(current, c.target))
+	if failures := changed.apply(nil, out, deviceID()); len(failures) > 0 {
+		failures.log()
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/google/subcommands"
+)
+
+// settingsVersion is the version of the settings file format written by
+// this version of the program. It must be increased, and a migration
+// added, whenever a change means that older files need converting.
+const settingsVersion = 2
+
+// settingsMigration upgrades a settings file from one version to the next.
+type settingsMigration struct {
+	// renames maps the old names of registers and text registers that
+	// were renamed to their new names.
+	renames map[string]string
+	// upgrade makes any other changes needed, such as converting values
+	// to a new encoding.
+	upgrade func(*settingsFile) error
+}
+
+// settingsMigrations[i] upgrades a file from version i to version i+1.
+var settingsMigrations = [settingsVersion]settingsMigration{
+	// Version 0 files were just a map of register values, which
+	// loadSettingsFile wraps, so that is all there is to it.
+	{},
+	// Version 2 added the version field.
+	{},
+}
+
+// rename moves the values of renamed registers to their new names. If a
+// file somehow has values under both names, the new one is kept.
+func (m settingsMigration) rename(f *settingsFile) {
+	for old, name := range m.renames {
+		if v, ok := f.Registers[old]; ok {
+			delete(f.Registers, old)
+			if _, ok := f.Registers[name]; !ok {
+				f.Registers[name] = v
+			}
+		}
+		if v, ok := f.Text[old]; ok {
+			delete(f.Text, old)
+			if _, ok := f.Text[name]; !ok {
+				f.Text[name] = v
+			}
+		}
+	}
+}
+
+// migrate upgrades the file to the current version of the format.
+func (f *settingsFile) migrate() error {
+	if f.Version > settingsVersion {
+		return fmt.Errorf("file format version %d is newer than this version of sc55ctl understands (%d); upgrade sc55ctl", f.Version, settingsVersion)
+	}
+	for v := f.Version; v < settingsVersion; v++ {
+		m := settingsMigrations[v]
+		m.rename(f)
+		if m.upgrade != nil {
+			if err := m.upgrade(f); err != nil {
+				return fmt.Errorf("upgrading from version %d: %v", v, err)
+			}
+		}
+	}
+	f.Version = settingsVersion
+	return nil
+}
+
+type migrateCommand struct {
+	dryRun bool
+	backup bool
+}
+
+func (*migrateCommand) Name() string { return "migrate" }
+func (*migrateCommand) Synopsis() string {
+	return "upgrade settings and checkpoint files saved by older versions to the current format"
+}
+func (*migrateCommand) Usage() string {
+	return `migrate [flags] <file>...:
+Rewrites each file in the current settings file format, renaming
+registers and converting values as needed. Old files can still be loaded
+by every command without this, but migrating them keeps them readable by
+other tools and makes later upgrades unnecessary. Files that are already
+current are left alone.
+`
+}
+
+func (c *migrateCommand) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&c.dryRun, "dry_run", false, "only show which files need upgrading")
+	f.BoolVar(&c.backup, "backup", true, "keep the original of each upgraded file, with .bak appended to its name")
+}
+
+// migrateFile upgrades a single file, returning the version it had.
+func (c *migrateCommand) migrateFile(filename string) (int, error) {
+	sf, err := loadSettingsFile(filename)
+	if err != nil {
+		return 0, err
+	}
+	if sf.loadedVersion == settingsVersion || c.dryRun {
+		return sf.loadedVersion, nil
+	}
+	if c.backup {
+		if err := os.Rename(filename, filename+".bak"); err != nil {
+			return 0, err
+		}
+	}
+	return sf.loadedVersion, sf.save(filename)
+}
+
+func (c *migrateCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) == 0 {
+		log.Printf("no files to migrate")
+		return subcommands.ExitUsageError
+	}
+	result := subcommands.ExitSuccess
+	for _, filename := range f.Args() {
+		v, err := c.migrateFile(filename)
+		switch {
+		case err != nil:
+			log.Printf("failed to migrate %s: %v", filename, err)
+			result = subcommands.ExitFailure
+		case v == settingsVersion:
+			fmt.Printf("%s: already version %d\n", filename, v)
+		case c.dryRun:
+			fmt.Printf("%s: version %d, would upgrade to %d\n", filename, v, settingsVersion)
+		default:
+			fmt.Printf("%s: upgraded from version %d to %d\n", filename, v, settingsVersion)
+		}
+	}
+	return result
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+// unit is one of the two SoundCanvas modules being kept in sync.
+type unit struct {
+	in, out *portmidi.Stream
+	id      sc55.DeviceID
+}
+
+func openUnit(portName string, id sc55.DeviceID) (*unit, error) {
+	in, err := openPort(portName, false)
+	if err != nil {
+		return nil, err
+	}
+	out, err := openPort(portName, true)
+	if err != nil {
+		return nil, err
+	}
+	return &unit{in: in, out: out, id: id}, nil
+}
+
+func (u *unit) set(r *sc55.Register, value int) error {
+	msg, err := r.Set(u.id, value)
+	if err != nil {
+		return err
+	}
+	return writeSysEx(u.out, msg)
+}
+
+type mirrorCommand struct {
+	interval, timeout time.Duration
+	all               bool
+	bidirectional     bool
+	targetDevice      string
+	targetDeviceID    int
+}
+
+func (*mirrorCommand) Name() string { return "mirror" }
+func (*mirrorCommand) Synopsis() string {
+	return "keep the registers of a second SoundCanvas in sync with the first"
+}
+func (*mirrorCommand) Usage() string { return "" }
+
+func (c *mirrorCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setDisplayErrorFlags(f)
+	f.DurationVar(&c.interval, "interval", time.Second, "how often to compare registers on the two devices, unless poll_intervals in the config file sets a rate for their tags")
+	f.DurationVar(&c.timeout, "timeout", 100*time.Millisecond, "how long to wait for a reply from a SoundCanvas before timing out")
+	f.BoolVar(&c.all, "all", false, "mirror all registers, not just the important ones")
+	f.BoolVar(&c.bidirectional, "bidirectional", false, "also copy changes made on the second device back to the first")
+	f.StringVar(&c.targetDevice, "target_midi_device", "", "Name of the MIDI device the second SoundCanvas is connected to")
+	f.IntVar(&c.targetDeviceID, "target_sc55_device_id", int(sc55.DefaultDevice), "ID of the second SoundCanvas")
+}
+
+// sync makes one pass over the registers, copying any values that differ
+// between the two devices. last holds the values seen on the previous pass,
+// which is how the direction of a change is determined in bidirectional
+// mode.
+func (c *mirrorCommand) sync(primary, secondary *unit, regs []*sc55.Register, last map[*sc55.Register]int) error {
+	for _, r := range regs {
+		pv, err := queryRegister(primary.in, primary.out, primary.id, r, c.timeout)
+		if err != nil {
+			log.Printf("error querying register %q on primary: %v", r.Name(), err)
+			continue
+		}
+		sv, err := queryRegister(secondary.in, secondary.out, secondary.id, r, c.timeout)
+		if err != nil {
+			log.Printf("error querying register %q on secondary: %v", r.Name(), err)
+			continue
+		}
+		prev, seen := last[r]
+		switch {
+		case pv == sv:
+			last[r] = pv
+		case c.bidirectional && seen && pv == prev:
+			// Only the secondary changed since last time.
+			log.Printf("%s: %d -> %d (from secondary)", r.Name(), pv, sv)
+			if err := primary.set(r, sv); err != nil {
+				return err
+			}
+			last[r] = sv
+		default:
+			log.Printf("%s: %d -> %d", r.Name(), sv, pv)
+			if err := secondary.set(r, pv); err != nil {
+				return err
+			}
+			last[r] = pv
+		}
+	}
+	return nil
+}
+
+func (c *mirrorCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	if c.targetDevice == "" {
+		log.Printf("-target_midi_device must be specified")
+		return subcommands.ExitUsageError
+	}
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer displayPanics(out)
+	primary := &unit{in: in, out: out, id: deviceID()}
+	secondary, err := openUnit(c.targetDevice, sc55.DeviceID(c.targetDeviceID))
+	if err != nil {
+		log.Printf("failed to open target device: %v", err)
+		displayError(out, errCodeDevice)
+		return subcommands.ExitFailure
+	}
+	regs := sc55.AllRegisters()
+	if !c.all {
+		regs = onlyImportant(regs)
+	}
+	last := make(map[*sc55.Register]int)
+	sched := newPollScheduler(regs, pollIntervals, c.interval)
+	for {
+		if err := c.sync(primary, secondary, sched.due(time.Now()), last); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			displayError(out, errCodeWrite)
+			return subcommands.ExitFailure
+		}
+		time.Sleep(time.Until(sched.next()))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/google/subcommands"
+)
+
+type normalizeCommand struct{}
+
+func (*normalizeCommand) Name() string { return "normalize" }
+func (*normalizeCommand) Synopsis() string {
+	return "rewrite settings files in canonical form so that they diff cleanly"
+}
+func (*normalizeCommand) Usage() string { return "normalize <file>...:\n" }
+
+func (*normalizeCommand) SetFlags(*flag.FlagSet) {}
+
+func (*normalizeCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) == 0 {
+		log.Printf("no files to normalize")
+		return subcommands.ExitUsageError
+	}
+	result := subcommands.ExitSuccess
+	for _, filename := range f.Args() {
+		sf, err := loadSettingsFile(filename)
+		if err == nil {
+			err = sf.save(filename)
+		}
+		if err != nil {
+			log.Printf("failed to normalize %s: %v", filename, err)
+			result = subcommands.ExitFailure
+		}
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type partSetupCommand struct {
+	instrument string
+	channel    string
+	drums      string
+	level      string
+	pan        string
+	reverb     string
+	chorus     string
+	checkpoint bool
+}
+
+func (*partSetupCommand) Name() string { return "part-setup" }
+func (*partSetupCommand) Synopsis() string {
+	return "set the instrument, channel, level, pan and effect sends of a part at once"
+}
+func (*partSetupCommand) Usage() string {
+	return `part-setup [flags] <part>:
+Applies the given settings to a part in as few messages as possible; any
+setting not given is left unchanged. For example:
+
+  part-setup -drums map1 -level 100 10
+
+Values can be given in the same forms as for set (eg. -pan L32).
+`
+}
+
+func (c *partSetupCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.instrument, "instrument", "", "instrument to select, by name or as \"<bank> <program>\"")
+	f.StringVar(&c.channel, "channel", "", "MIDI channel (1-16) for the part to receive on, or \"off\"")
+	f.StringVar(&c.drums, "drums", "", "drum map for the part to play (map1 or map2), or \"off\" for a normal part")
+	f.StringVar(&c.level, "level", "", "part level")
+	f.StringVar(&c.pan, "pan", "", "pan position")
+	f.StringVar(&c.reverb, "reverb", "", "reverb send level")
+	f.StringVar(&c.chorus, "chorus", "", "chorus send level")
+	setCheckpointFlag(f, &c.checkpoint, true)
+}
+
+// optionalValue parses the value of a flag for the given register, or
+// returns nil if the flag wasn't given.
+func optionalValue(r *sc55.Register, s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := r.ParseValue(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// setup returns the settings chosen by the flags.
+func (c *partSetupCommand) setup(p *sc55.Part) (sc55.PartSetup, error) {
+	s := sc55.PartSetup{}
+	if c.instrument != "" {
+		t, err := parseTone(strings.Fields(c.instrument))
+		if err != nil {
+			return s, err
+		}
+		s.Tone = &t
+	}
+	switch c.channel {
+	case "":
+	case "off":
+		s.Channel = new(int)
+	default:
+		ch, err := strconv.Atoi(c.channel)
+		if err != nil {
+			return s, fmt.Errorf("invalid channel %q", c.channel)
+		}
+		s.Channel = &ch
+	}
+	var err error
+	for _, v := range []struct {
+		dest **int
+		r    *sc55.Register
+		s    string
+	}{
+		{&s.DrumMap, &p.UseForRhythm, c.drums},
+		{&s.Level, &p.PartLevel, c.level},
+		{&s.Pan, &p.PanPot, c.pan},
+		{&s.Reverb, &p.ReverbSendLevel, c.reverb},
+		{&s.Chorus, &p.ChorusSendLevel, c.chorus},
+	} {
+		if *v.dest, err = optionalValue(v.r, v.s); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
+
+func (c *partSetupCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	part, err := strconv.Atoi(f.Args()[0])
+	p := sc55.PartByNumber(part)
+	if err != nil || p == nil {
+		log.Printf("invalid part number %q", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	s, err := c.setup(p)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	msgs, err := p.Setup(deviceID(), s)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if len(msgs) == 0 {
+		log.Printf("nothing to change; see -help for the settings that can be given")
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint (use -checkpoint=false to go ahead without one): %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	for i, msg := range msgs {
+		if i > 0 {
+			time.Sleep(bulkWriteDelay)
+		}
+		if err := writeSysEx(out, msg); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/rakyll/portmidi"
+)
+
+const (
+	statusNoteOff       = 0x80
+	statusNoteOn        = 0x90
+	statusPolyPressure  = 0xa0
+	statusControlChange = 0xb0
+	statusProgramChange = 0xc0
+	statusPitchBend     = 0xe0
+)
+
+// transform is one stage of the proxy pipeline. It returns the events to
+// pass on to the next stage: none to drop the event, or several to add
+// extra events.
+type transform interface {
+	apply(e portmidi.Event) []portmidi.Event
+}
+
+// ticker is implemented by transforms that generate events by themselves
+// as time passes, such as the arpeggiator. tick is called frequently with
+// the current time and returns any events that are due.
+type ticker interface {
+	tick(now portmidi.Timestamp) []portmidi.Event
+}
+
+// pipeline is a chain of transforms applied in order.
+type pipeline []transform
+
+func (p pipeline) apply(e portmidi.Event) []portmidi.Event {
+	return p.applyFrom(0, []portmidi.Event{e})
+}
+
+// applyFrom passes the given events through the stages of the pipeline
+// starting at the given index.
+func (p pipeline) applyFrom(stage int, events []portmidi.Event) []portmidi.Event {
+	for _, t := range p[stage:] {
+		next := []portmidi.Event{}
+		for _, e := range events {
+			next = append(next, t.apply(e)...)
+		}
+		events = next
+	}
+	return events
+}
+
+// tick collects the events generated by any tickers in the pipeline, each
+// passed through the stages after the one that generated it.
+func (p pipeline) tick(now portmidi.Timestamp) []portmidi.Event {
+	result := []portmidi.Event{}
+	for i, t := range p {
+		if tk, ok := t.(ticker); ok {
+			result = append(result, p.applyFrom(i+1, tk.tick(now))...)
+		}
+	}
+	return result
+}
+
+func isChannelMessage(e portmidi.Event) bool {
+	return e.SysEx == nil && e.Status >= 0x80 && e.Status < 0xf0
+}
+
+// eventChannel returns the channel (1-16) of a channel message.
+func eventChannel(e portmidi.Event) int {
+	return int(e.Status&0x0f) + 1
+}
+
+func isNote(e portmidi.Event) bool {
+	status := e.Status & 0xf0
+	return isChannelMessage(e) && (status == statusNoteOn || status == statusNoteOff || status == statusPolyPressure)
+}
+
+// channelSet is a set of channels numbered 1-16. An empty set matches all
+// channels.
+type channelSet []int
+
+func (cs channelSet) matches(e portmidi.Event) bool {
+	if !isChannelMessage(e) {
+		return false
+	}
+	if len(cs) == 0 {
+		return true
+	}
+	ch := eventChannel(e)
+	for _, c := range cs {
+		if c == ch {
+			return true
+		}
+	}
+	return false
+}
+
+type transposeTransform struct {
+	Semitones int        `json:"semitones"`
+	Channels  channelSet `json:"channels"`
+}
+
+func (t *transposeTransform) apply(e portmidi.Event) []portmidi.Event {
+	if isNote(e) && t.Channels.matches(e) {
+		note := e.Data1 + int64(t.Semitones)
+		if note < 0 || note > 0x7f {
+			// Can't be played; drop rather than fold into range.
+			return nil
+		}
+		e.Data1 = note
+	}
+	return []portmidi.Event{e}
+}
+
+type channelFilterTransform struct {
+	Channels channelSet `json:"channels"`
+}
+
+func (t *channelFilterTransform) apply(e portmidi.Event) []portmidi.Event {
+	if isChannelMessage(e) && !t.Channels.matches(e) {
+		return nil
+	}
+	return []portmidi.Event{e}
+}
+
+type velocityCurveTransform struct {
+	// Gamma shapes the curve: values below 1 make playing louder, values
+	// above 1 make it softer.
+	Gamma    float64    `json:"gamma"`
+	Min      int64      `json:"min"`
+	Max      int64      `json:"max"`
+	Channels channelSet `json:"channels"`
+}
+
+func (t *velocityCurveTransform) apply(e portmidi.Event) []portmidi.Event {
+	if e.Status&0xf0 == statusNoteOn && e.Data2 > 0 && t.Channels.matches(e) {
+		v := math.Pow(float64(e.Data2)/127, t.Gamma)
+		e.Data2 = t.Min + int64(math.Round(v*float64(t.Max-t.Min)))
+		if e.Data2 < 1 {
+			// Velocity zero would turn it into a note off.
+			e.Data2 = 1
+		}
+	}
+	return []portmidi.Event{e}
+}
+
+// ccRemapTransform changes the controller number of control changes, eg. to
+// turn the sustain pedal into sostenuto (64 to 66) or expression into
+// volume (11 to 7). A single controller can be given with from and to, or
+// several at once with a table.
+type ccRemapTransform struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+	// Map is a table of controller numbers to remap, eg. {"64": 66}.
+	// Each event is only remapped once, so controllers can be swapped.
+	Map      map[string]int64 `json:"map"`
+	Channels channelSet       `json:"channels"`
+
+	table map[int64]int64
+}
+
+func (t *ccRemapTransform) UnmarshalJSON(data []byte) error {
+	type plainCCRemap ccRemapTransform
+	if err := json.Unmarshal(data, (*plainCCRemap)(t)); err != nil {
+		return err
+	}
+	t.table = map[int64]int64{}
+	if t.From != t.To {
+		t.table[t.From] = t.To
+	}
+	for from, to := range t.Map {
+		cc, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid controller number %q in cc-remap table", from)
+		}
+		t.table[cc] = to
+	}
+	for from, to := range t.table {
+		if from < 0 || from > 0x7f || to < 0 || to > 0x7f {
+			return fmt.Errorf("invalid cc-remap from %d to %d, want 0 <= x <= 127", from, to)
+		}
+	}
+	return nil
+}
+
+func (t *ccRemapTransform) apply(e portmidi.Event) []portmidi.Event {
+	if e.Status&0xf0 != statusControlChange || !t.Channels.matches(e) {
+		return []portmidi.Event{e}
+	}
+	if to, ok := t.table[e.Data1]; ok {
+		e.Data1 = to
+	}
+	return []portmidi.Event{e}
+}
+
+type sysExRewriteTransform struct {
+	// DeviceID, if set, readdresses Roland SysEx messages to the given
+	// device.
+	DeviceID int `json:"device_id"`
+	// Drop discards all SysEx messages.
+	Drop bool `json:"drop"`
+}
+
+func (t *sysExRewriteTransform) apply(e portmidi.Event) []portmidi.Event {
+	if e.SysEx == nil {
+		return []portmidi.Event{e}
+	}
+	if t.Drop {
+		return nil
+	}
+	if t.DeviceID != 0 && len(e.SysEx) > 3 && e.SysEx[1] == sc55.ManufacturerID {
+		msg := append([]byte{}, e.SysEx...)
+		msg[2] = byte(t.DeviceID)
+		e.SysEx = msg
+	}
+	return []portmidi.Event{e}
+}
+
+// coalescedWrite identifies the memory written by a DT1 message.
+type coalescedWrite struct {
+	device  sc55.DeviceID
+	modelID byte
+	address int
+}
+
+// sysExCoalesceTransform holds back DT1 messages and sends only the latest
+// one for each address, at most once per interval. Sliders in an editor
+// send a message for every step they move through, which can flood the
+// device and cause audible zipper noise; this keeps just the value the
+// slider has reached.
+type sysExCoalesceTransform struct {
+	IntervalMS int64 `json:"interval_ms"`
+
+	pending map[coalescedWrite][]byte
+	order   []coalescedWrite
+	next    portmidi.Timestamp
+}
+
+func (t *sysExCoalesceTransform) apply(e portmidi.Event) []portmidi.Event {
+	if e.SysEx == nil || sc55.Classify(e.SysEx) != sc55.MessageDT1 {
+		return []portmidi.Event{e}
+	}
+	dev, addr, _, err := sc55.UnmarshalSet(e.SysEx)
+	if err != nil {
+		return []portmidi.Event{e}
+	}
+	key := coalescedWrite{dev, e.SysEx[3], addr}
+	if t.pending == nil {
+		t.pending = map[coalescedWrite][]byte{}
+	}
+	if _, ok := t.pending[key]; !ok {
+		t.order = append(t.order, key)
+	}
+	t.pending[key] = e.SysEx
+	return nil
+}
+
+func (t *sysExCoalesceTransform) tick(now portmidi.Timestamp) []portmidi.Event {
+	if len(t.order) == 0 || now < t.next {
+		return nil
+	}
+	result := []portmidi.Event{}
+	for _, key := range t.order {
+		result = append(result, portmidi.Event{Timestamp: now, SysEx: t.pending[key]})
+		delete(t.pending, key)
+	}
+	t.order = t.order[:0]
+	t.next = now + portmidi.Timestamp(t.IntervalMS)
+	return result
+}
+
+// keyboardZone is a range of notes played on a channel, for splits and
+// layers.
+type keyboardZone struct {
+	Low       int64 `json:"low"`
+	High      int64 `json:"high"`
+	Channel   int   `json:"channel"`
+	Transpose int64 `json:"transpose"`
+}
+
+func (z *keyboardZone) UnmarshalJSON(data []byte) error {
+	type plainZone keyboardZone
+	p := plainZone{High: 0x7f}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	if p.Channel < 1 || p.Channel > 16 {
+		return fmt.Errorf("invalid zone channel %d, want 1 <= x <= 16", p.Channel)
+	}
+	*z = keyboardZone(p)
+	return nil
+}
+
+// zonesTransform sends each note to every zone whose range contains it, so
+// that overlapping zones make layers and adjacent zones make splits. Other
+// channel messages, such as controllers and pitch bend, are sent to every
+// zone's channel, except for program changes, which are dropped so that
+// each part keeps its own instrument.
+type zonesTransform struct {
+	Zones    []keyboardZone `json:"zones"`
+	Channels channelSet     `json:"channels"`
+}
+
+func withChannel(e portmidi.Event, channel int) portmidi.Event {
+	e.Status = e.Status&0xf0 | int64(channel-1)
+	return e
+}
+
+func (t *zonesTransform) apply(e portmidi.Event) []portmidi.Event {
+	if !t.Channels.matches(e) {
+		return []portmidi.Event{e}
+	}
+	result := []portmidi.Event{}
+	if isNote(e) {
+		for _, z := range t.Zones {
+			note := e.Data1 + z.Transpose
+			if e.Data1 < z.Low || e.Data1 > z.High || note < 0 || note > 0x7f {
+				continue
+			}
+			out := withChannel(e, z.Channel)
+			out.Data1 = note
+			result = append(result, out)
+		}
+		return result
+	}
+	if e.Status&0xf0 == statusProgramChange {
+		return nil
+	}
+	sent := map[int]bool{}
+	for _, z := range t.Zones {
+		if !sent[z.Channel] {
+			result = append(result, withChannel(e, z.Channel))
+			sent[z.Channel] = true
+		}
+	}
+	return result
+}
+
+// transformTypes maps the "type" field in a pipeline file to a constructor
+// for the transform.
+var transformTypes = map[string]func() transform{
+	"transpose":      func() transform { return &transposeTransform{} },
+	"channel-filter": func() transform { return &channelFilterTransform{} },
+	"velocity-curve": func() transform { return &velocityCurveTransform{Gamma: 1, Max: 127} },
+	"cc-remap":       func() transform { return &ccRemapTransform{} },
+	"sysex-rewrite":  func() transform { return &sysExRewriteTransform{} },
+	"sysex-coalesce": func() transform { return &sysExCoalesceTransform{IntervalMS: 20} },
+	"zones":          func() transform { return &zonesTransform{} },
+	"echo":           func() transform { return &echoTransform{DelayMS: 250, Repeats: 3, Feedback: 0.5} },
+	"arpeggiator":    func() transform { return newArpeggiator() },
+}
+
+// loadPipeline reads a pipeline file, which is a JSON list of transforms,
+// each an object with a "type" field and the parameters for that type.
+func loadPipeline(filename string) (pipeline, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var stages []json.RawMessage
+	if err := json.Unmarshal(data, &stages); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	result := pipeline{}
+	for i, stage := range stages {
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(stage, &header); err != nil {
+			return nil, fmt.Errorf("%s: stage %d: %v", filename, i+1, err)
+		}
+		newTransform, ok := transformTypes[header.Type]
+		if !ok {
+			return nil, fmt.Errorf("%s: stage %d: unknown transform type %q", filename, i+1, header.Type)
+		}
+		t := newTransform()
+		if err := json.Unmarshal(stage, t); err != nil {
+			return nil, fmt.Errorf("%s: stage %d: %v", filename, i+1, err)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+)
+
+// pollIntervals are the refresh intervals for each register tag, from the
+// config file.
+var pollIntervals = map[sc55.Tag]time.Duration{}
+
+// pollGroup is a set of registers that are refreshed at the same rate.
+type pollGroup struct {
+	interval time.Duration
+	regs     []*sc55.Register
+	next     time.Time
+}
+
+// pollScheduler decides which registers to read on each pass when
+// watching a device, so that registers that change often can be refreshed
+// more often than those that rarely do, without reading everything every
+// time.
+type pollScheduler struct {
+	groups []*pollGroup
+}
+
+// newPollScheduler returns a scheduler for the given registers. Each
+// register is refreshed at the shortest of the intervals given for its
+// tags, or at the default interval if none of its tags have one. All the
+// registers are due straight away.
+func newPollScheduler(regs []*sc55.Register, intervals map[sc55.Tag]time.Duration, defaultInterval time.Duration) *pollScheduler {
+	byInterval := map[time.Duration]*pollGroup{}
+	for _, r := range regs {
+		interval := time.Duration(0)
+		for _, t := range r.Tags() {
+			if d, ok := intervals[t]; ok && (interval == 0 || d < interval) {
+				interval = d
+			}
+		}
+		if interval == 0 {
+			interval = defaultInterval
+		}
+		g, ok := byInterval[interval]
+		if !ok {
+			g = &pollGroup{interval: interval}
+			byInterval[interval] = g
+		}
+		g.regs = append(g.regs, r)
+	}
+	s := &pollScheduler{}
+	for _, g := range byInterval {
+		s.groups = append(s.groups, g)
+	}
+	sort.Slice(s.groups, func(i, j int) bool { return s.groups[i].interval < s.groups[j].interval })
+	return s
+}
+
+// due returns the registers that are due to be refreshed at the given
+// time, and schedules their next refresh.
+func (s *pollScheduler) due(now time.Time) []*sc55.Register {
+	result := []*sc55.Register{}
+	for _, g := range s.groups {
+		if now.Before(g.next) {
+			continue
+		}
+		result = append(result, g.regs...)
+		g.next = g.next.Add(g.interval)
+		if g.next.Before(now) {
+			// Fallen behind (or the first pass); don't try to catch up.
+			g.next = now.Add(g.interval)
+		}
+	}
+	return result
+}
+
+// next returns the time that the next registers are due.
+func (s *pollScheduler) next() time.Time {
+	var result time.Time
+	for i, g := range s.groups {
+		if i == 0 || g.next.Before(result) {
+			result = g.next
+		}
+	}
+	return result
+}
+
+// parsePollIntervals parses the poll_intervals setting from the config
+// file, which maps register tags to duration strings.
+func parsePollIntervals(intervals map[string]string) (map[sc55.Tag]time.Duration, error) {
+	result := map[sc55.Tag]time.Duration{}
+	for name, value := range intervals {
+		tags, err := sc55.ParseTags(name)
+		if err != nil {
+			return nil, err
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid poll interval %q for %q", value, name)
+		}
+		for _, t := range tags {
+			result[t] = d
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// provisionTarget is one entry in the list of units to provision.
+type provisionTarget struct {
+	MIDIDevice string `json:"midi_device"`
+	DeviceID   int    `json:"device_id"`
+	Name       string `json:"name"`
+}
+
+// inventoryEntry records the result of provisioning one unit.
+type inventoryEntry struct {
+	provisionTarget
+	Time      time.Time `json:"time"`
+	Responded bool      `json:"responded"`
+	// Identity is the unit's reply to an identity request, if any, and
+	// Model is the model found by probing it as identify does.
+	Identity *inventoryIdentity `json:"identity,omitempty"`
+	Model    string             `json:"model,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// inventoryIdentity is an identity reply, as recorded in the inventory.
+type inventoryIdentity struct {
+	Family      int    `json:"family"`
+	Member      int    `json:"member"`
+	Version     string `json:"version"`
+	Description string `json:"description"`
+}
+
+type provisionCommand struct {
+	settingsFile  string
+	inventoryFile string
+	timeout       time.Duration
+}
+
+func (*provisionCommand) Name() string { return "provision" }
+func (*provisionCommand) Synopsis() string {
+	return "apply common settings to a list of SoundCanvas units"
+}
+func (*provisionCommand) Usage() string {
+	return `provision [flags] <units.json>:
+The units file is a JSON list of objects with "midi_device", "device_id" and
+"name" fields. Each unit gets the common settings applied and its name shown
+on its display, and the results are written to an inventory file along
+with what the unit reports about itself (see identify).
+`
+}
+
+func (c *provisionCommand) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&c.settingsFile, "settings", "", "settings file to apply to every unit")
+	f.StringVar(&c.inventoryFile, "inventory", "inventory.json", "file to write provisioning results to")
+	f.DurationVar(&c.timeout, "timeout", 100*time.Millisecond, "how long to wait for a reply from a SoundCanvas before timing out")
+}
+
+func loadProvisionTargets(filename string) ([]provisionTarget, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var targets []provisionTarget
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	for i := range targets {
+		// Treat an omitted device ID as the default rather than zero.
+		if targets[i].DeviceID == 0 {
+			targets[i].DeviceID = int(sc55.DefaultDevice)
+		}
+	}
+	return targets, nil
+}
+
+// provision configures a single unit, filling in the inventory entry for
+// it. Responded is set if the unit replied to a query, which confirms that
+// the port and device ID are correct.
+func (c *provisionCommand) provision(t provisionTarget, s settings, entry *inventoryEntry) error {
+	in, err := openPort(t.MIDIDevice, false)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := openPort(t.MIDIDevice, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	dev := sc55.DeviceID(t.DeviceID)
+	if _, err := queryRegister(in, out, dev, &sc55.MasterVolume, c.timeout); err != nil {
+		return err
+	}
+	entry.Responded = true
+	// Older modules may not reply to identity requests, so a missing
+	// reply isn't an error.
+	id, ok, err := readIdentity(in, out, dev, c.timeout)
+	if err != nil {
+		return err
+	}
+	if ok {
+		entry.Identity = &inventoryIdentity{
+			Family:      id.Family,
+			Member:      id.Member,
+			Version:     fmt.Sprintf("% x", id.Version[:]),
+			Description: id.String(),
+		}
+	}
+	if m, err := probeModel(in, out, dev, c.timeout); err == nil {
+		entry.Model = m.String()
+	}
+	if err := s.apply(nil, out, dev).err(); err != nil {
+		return err
+	}
+	if t.Name != "" && sc55.CurrentProfile().Display {
+		if err := writeSysEx(out, sc55.DisplayMessage(dev, t.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *provisionCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("units file not provided")
+		return subcommands.ExitUsageError
+	}
+	targets, err := loadProvisionTargets(f.Args()[0])
+	if err != nil {
+		log.Printf("failed to load units: %v", err)
+		return subcommands.ExitFailure
+	}
+	s := settings{}
+	if c.settingsFile != "" {
+		s, err = loadSettings(c.settingsFile)
+		if err != nil {
+			log.Printf("failed to load settings: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	result := subcommands.ExitSuccess
+	inventory := []inventoryEntry{}
+	for _, t := range targets {
+		entry := inventoryEntry{
+			provisionTarget: t,
+			Time:            time.Now(),
+		}
+		if err := c.provision(t, s, &entry); err != nil {
+			log.Printf("failed to provision %q (device %q, ID %d): %v", t.Name, t.MIDIDevice, t.DeviceID, err)
+			entry.Error = err.Error()
+			result = subcommands.ExitFailure
+		}
+		inventory = append(inventory, entry)
+	}
+	data, err := json.MarshalIndent(inventory, "", "\t")
+	if err != nil {
+		log.Printf("failed to marshal inventory: %v", err)
+		return subcommands.ExitFailure
+	}
+	if err := os.WriteFile(c.inventoryFile, append(data, '\n'), 0644); err != nil {
+		log.Printf("failed to write inventory: %v", err)
+		return subcommands.ExitFailure
+	}
+	return result
+}
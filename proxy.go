@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+type proxyCommand struct {
+	inputDevice  string
+	pipelineFile string
+	activity     bool
+}
+
+func (*proxyCommand) Name() string { return "proxy" }
+func (*proxyCommand) Synopsis() string {
+	return "forward MIDI from another port to the SoundCanvas, transforming it on the way"
+}
+func (*proxyCommand) Usage() string { return "" }
+
+func (c *proxyCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setDisplayErrorFlags(f)
+	f.StringVar(&c.inputDevice, "input_midi_device", "", "Name of the MIDI device to read events from (eg. a keyboard or sequencer)")
+	f.StringVar(&c.pipelineFile, "pipeline", "", "JSON file listing the transforms to apply to each event")
+	f.BoolVar(&c.activity, "activity", false, "count the events sent on each channel, and print a summary when stopped with Ctrl-C")
+}
+
+// writeEvent sends a single event to the output stream.
+func writeEvent(out *portmidi.Stream, e portmidi.Event) error {
+	if e.SysEx != nil {
+		return writeSysEx(out, e.SysEx)
+	}
+	if readOnly {
+		return errReadOnly
+	}
+	return out.WriteShort(e.Status, e.Data1, e.Data2)
+}
+
+func (c *proxyCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	p := pipeline{}
+	if c.pipelineFile != "" {
+		var err error
+		p, err = loadPipeline(c.pipelineFile)
+		if err != nil {
+			log.Printf("failed to load pipeline: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	if c.activity {
+		// Counting last means that the summary shows what the
+		// SoundCanvas actually received.
+		a := &channelActivity{}
+		p = append(p, a)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt)
+		go func() {
+			<-stop
+			a.summary(os.Stdout)
+			os.Exit(0)
+		}()
+		log.Printf("counting channel activity; press Ctrl-C to stop")
+	}
+	if err := runProxy(c.inputDevice, p); err != nil {
+		log.Printf("proxy failed: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+// proxyReadEvents is the number of events the proxy reads from its input at
+// once.
+const proxyReadEvents = 256
+
+// runProxy forwards events from the named input port to the SoundCanvas,
+// passing each one through the pipeline. It only returns on error.
+func runProxy(inputDevice string, p pipeline) error {
+	return runProxyUntil(inputDevice, p, nil, nil)
+}
+
+// runProxyUntil is like runProxy, but returns nil once a value is received
+// from stop, and calls onEvent (if not nil) with each event that comes out
+// of the pipeline.
+func runProxyUntil(inputDevice string, p pipeline, stop <-chan os.Signal, onEvent func(portmidi.Event)) error {
+	out, err := openOutputStream()
+	if err != nil {
+		return fmt.Errorf("failed to open output: %v", err)
+	}
+	defer out.Close()
+	defer displayPanics(out)
+	in, err := openPort(inputDevice, false)
+	if err != nil {
+		displayError(out, errCodeDevice)
+		return fmt.Errorf("failed to open input: %v", err)
+	}
+	defer in.Close()
+	// Transforms such as echo produce events with timestamps in the
+	// future; they are held back until it's time to send them.
+	var pending eventQueue
+	send := func(e portmidi.Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+		if err := writeEvent(out, e); err != nil {
+			log.Printf("error writing event: %v", err)
+		}
+	}
+	for {
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+		now := portmidi.Time()
+		for _, e := range p.tick(now) {
+			pending.push(e)
+		}
+		for _, e := range pending.popDue(now) {
+			send(e)
+		}
+		ok, err := in.Poll()
+		if err != nil {
+			displayError(out, errCodeRead)
+			return fmt.Errorf("error reading input: %v", err)
+		}
+		if !ok {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		events, err := in.ReadEvents(proxyReadEvents, maxSysExSize)
+		switch {
+		case err == portmidi.ErrSysExOverflow:
+			log.Printf("error reading input: %v", errSysExTruncated)
+		case err != nil:
+			displayError(out, errCodeRead)
+			return fmt.Errorf("error reading input: %v", err)
+		}
+		for _, e := range events {
+			for _, e := range p.apply(e) {
+				if e.Timestamp > portmidi.Time() {
+					pending.push(e)
+					continue
+				}
+				send(e)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+// recordedEvent is a channel message captured by a midiRecorder.
+type recordedEvent struct {
+	tick int
+	msg  []byte
+}
+
+// recordedNote tracks a held note, so that its note off can be moved by
+// the same amount as its note on was quantized.
+type recordedNote struct {
+	raw, quantized int
+}
+
+// midiRecorder collects channel messages for writing to a MIDI file.
+type midiRecorder struct {
+	usPerQuarter int
+	// grid is the quantization step in ticks; zero disables quantization.
+	grid    int
+	start   portmidi.Timestamp
+	started bool
+	events  []recordedEvent
+	held    map[[2]byte]recordedNote
+}
+
+// newMIDIRecorder returns a recorder for the given tempo, quantizing note
+// starts to the given fraction of a whole note (eg. 16 for sixteenth
+// notes), or not at all if quantize is zero.
+func newMIDIRecorder(bpm, quantize int) *midiRecorder {
+	r := &midiRecorder{
+		usPerQuarter: 60000000 / bpm,
+		held:         map[[2]byte]recordedNote{},
+	}
+	if quantize > 0 {
+		r.grid = smfDivision * 4 / quantize
+	}
+	return r
+}
+
+// channelMessageLength returns the length of a channel message with the
+// given status byte, or zero if it is not a channel message.
+func channelMessageLength(status byte) int {
+	switch status & 0xf0 {
+	case 0x80, 0x90, 0xa0, 0xb0, 0xe0:
+		return 3
+	case 0xc0, 0xd0:
+		return 2
+	}
+	return 0
+}
+
+// add records an event. Anything other than a channel message is ignored.
+func (r *midiRecorder) add(e portmidi.Event) {
+	status := byte(e.Status)
+	n := channelMessageLength(status)
+	if e.SysEx != nil || n == 0 {
+		return
+	}
+	if !r.started {
+		r.start, r.started = e.Timestamp, true
+	}
+	ms := int(e.Timestamp - r.start)
+	tick := ms * 1000 * smfDivision / r.usPerQuarter
+	msg := []byte{status, byte(e.Data1), byte(e.Data2)}[:n]
+	key := [2]byte{status & 0x0f, msg[1]}
+	switch {
+	case isNoteOn(e) && r.grid > 0:
+		q := (tick + r.grid/2) / r.grid * r.grid
+		r.held[key] = recordedNote{tick, q}
+		tick = q
+	case isNoteOff(e):
+		if note, ok := r.held[key]; ok {
+			tick += note.quantized - note.raw
+			delete(r.held, key)
+		}
+	}
+	if tick < 0 {
+		tick = 0
+	}
+	r.events = append(r.events, recordedEvent{tick, msg})
+}
+
+// track returns the recorded events as a MIDI file track.
+func (r *midiRecorder) track() *smfTrack {
+	sort.SliceStable(r.events, func(i, j int) bool {
+		return r.events[i].tick < r.events[j].tick
+	})
+	t := &smfTrack{}
+	t.addTempo(0, r.usPerQuarter)
+	for _, e := range r.events {
+		t.addEvent(e.tick, e.msg...)
+	}
+	return t
+}
+
+type recordMIDICommand struct {
+	inputDevice  string
+	pipelineFile string
+	bpm          int
+	quantize     int
+}
+
+func (*recordMIDICommand) Name() string { return "record-midi" }
+func (*recordMIDICommand) Synopsis() string {
+	return "play through to the SoundCanvas from a keyboard, recording to a MIDI file"
+}
+func (*recordMIDICommand) Usage() string {
+	return `record-midi <out.mid>:
+Forwards events from the input device to the SoundCanvas like proxy, and
+records the channel messages that were sent. Press Ctrl-C to stop
+recording and write the file.
+`
+}
+
+func (c *recordMIDICommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setDisplayErrorFlags(f)
+	f.StringVar(&c.inputDevice, "input_midi_device", "", "Name of the MIDI device to read events from (eg. a keyboard or sequencer)")
+	f.StringVar(&c.pipelineFile, "pipeline", "", "JSON file listing the transforms to apply to each event")
+	f.IntVar(&c.bpm, "bpm", 120, "tempo to write to the file, in beats per minute")
+	f.IntVar(&c.quantize, "quantize", 0, "quantize note starts to this fraction of a whole note, eg. 16 for sixteenth notes; 0 to record as played")
+}
+
+func (c *recordMIDICommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	if c.bpm <= 0 || c.quantize < 0 {
+		log.Printf("-bpm must be positive and -quantize must not be negative")
+		return subcommands.ExitUsageError
+	}
+	p := pipeline{}
+	if c.pipelineFile != "" {
+		var err error
+		p, err = loadPipeline(c.pipelineFile)
+		if err != nil {
+			log.Printf("failed to load pipeline: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	r := newMIDIRecorder(c.bpm, c.quantize)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt)
+	log.Printf("recording; press Ctrl-C to stop")
+	// Whatever was recorded is still saved if the proxy fails.
+	proxyErr := runProxyUntil(c.inputDevice, p, stop, r.add)
+	if proxyErr != nil {
+		log.Printf("proxy failed: %v", proxyErr)
+	}
+	if err := writeSMF(f.Args()[0], r.track()); err != nil {
+		log.Printf("failed to write MIDI file: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("%d events written to %s", len(r.events), f.Args()[0])
+	if proxyErr != nil {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
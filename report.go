@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// reportPartColumns are the part registers shown in the parts table, unless
+// all registers are requested.
+var reportPartColumns = []string{
+	"rx-channel", "tone-number-cc", "use-for-rhythm", "part-level",
+	"pan-pot", "pitch-key-shift", "reverb-send-level", "chorus-send-level",
+	"key-range-low", "key-range-high",
+}
+
+// effectPrefixes are the prefixes of register names shown in the effects
+// table.
+var effectPrefixes = []string{"reverb-", "chorus-", "delay-", "eq-", "efx-"}
+
+func isEffectRegister(r *sc55.Register) bool {
+	for _, prefix := range effectPrefixes {
+		if strings.HasPrefix(r.Name(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportSection is one table in a report.
+type reportSection struct {
+	Title   string
+	Columns []string
+	Rows    [][]string
+}
+
+// reportSections arranges settings into tables of system parameters, effects
+// and parts.
+func reportSections(s settings, allColumns bool) []reportSection {
+	system := reportSection{Title: "System", Columns: []string{"Parameter", "Value"}}
+	effects := reportSection{Title: "Effects", Columns: []string{"Parameter", "Value"}}
+	parts := reportSection{Title: "Parts", Columns: []string{"Part"}}
+	columns := reportPartColumns
+	if allColumns {
+		columns = nil
+		for _, r := range sc55.AllRegisters() {
+			if name := r.Name(); strings.HasPrefix(name, "part-1.") {
+				columns = append(columns, strings.TrimPrefix(name, "part-1."))
+			}
+		}
+	}
+	regs, _ := s.registers()
+	for _, r := range regs {
+		if strings.HasPrefix(r.Name(), "part-") || strings.HasPrefix(r.Name(), "drum-") {
+			continue
+		}
+		row := []string{r.Name(), r.FormatValue(s[r.Name()])}
+		if isEffectRegister(r) {
+			effects.Rows = append(effects.Rows, row)
+		} else {
+			system.Rows = append(system.Rows, row)
+		}
+	}
+	usedColumns := []string{}
+	for _, col := range columns {
+		for i := 1; i <= sc55.PartCount(); i++ {
+			if _, ok := s[fmt.Sprintf("part-%d.%s", i, col)]; ok {
+				usedColumns = append(usedColumns, col)
+				break
+			}
+		}
+	}
+	parts.Columns = append(parts.Columns, usedColumns...)
+	for i := 1; i <= sc55.PartCount(); i++ {
+		row := []string{fmt.Sprintf("%d", i)}
+		for _, col := range usedColumns {
+			name := fmt.Sprintf("part-%d.%s", i, col)
+			value, ok := s[name]
+			r, _ := sc55.RegisterByName(name)
+			if !ok || r == nil {
+				row = append(row, "")
+				continue
+			}
+			row = append(row, r.FormatValue(value))
+		}
+		parts.Rows = append(parts.Rows, row)
+	}
+	result := []reportSection{}
+	sections := []reportSection{system, effects, parts}
+	if allColumns {
+		sections = append(sections, drumSection(s))
+	}
+	for _, section := range sections {
+		if len(section.Rows) > 0 && len(section.Columns) > 1 {
+			result = append(result, section)
+		}
+	}
+	return result
+}
+
+// drumSection lists the drum setup registers, one row per note that has any
+// values in the settings.
+func drumSection(s settings) reportSection {
+	drums := reportSection{Title: "Drums", Columns: []string{"Map", "Note"}}
+	columns := []string{}
+	for _, r := range sc55.AllRegisters() {
+		prefix := fmt.Sprintf("drum-1.note-%d.", sc55.DrumNoteMin)
+		if name := r.Name(); strings.HasPrefix(name, prefix) {
+			columns = append(columns, strings.TrimPrefix(name, prefix))
+		}
+	}
+	drums.Columns = append(drums.Columns, columns...)
+	for m := 1; m <= sc55.NumDrumMaps; m++ {
+		for note := sc55.DrumNoteMin; note <= sc55.DrumNoteMax; note++ {
+			row := []string{fmt.Sprintf("%d", m), fmt.Sprintf("%d", note)}
+			found := false
+			for _, col := range columns {
+				name := fmt.Sprintf("drum-%d.note-%d.%s", m, note, col)
+				value, ok := s[name]
+				r, _ := sc55.RegisterByName(name)
+				if !ok || r == nil {
+					row = append(row, "")
+					continue
+				}
+				row = append(row, r.FormatValue(value))
+				found = true
+			}
+			if found {
+				drums.Rows = append(drums.Rows, row)
+			}
+		}
+	}
+	return drums
+}
+
+func writeTextReport(w io.Writer, sections []reportSection) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, section := range sections {
+		fmt.Fprintf(tw, "%s\n%s\n\n", section.Title, strings.Repeat("=", len(section.Title)))
+		fmt.Fprintln(tw, strings.Join(section.Columns, "\t"))
+		for _, row := range section.Rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		fmt.Fprintln(tw)
+	}
+	return tw.Flush()
+}
+
+func writeMarkdownReport(w io.Writer, sections []reportSection) error {
+	for _, section := range sections {
+		fmt.Fprintf(w, "## %s\n\n", section.Title)
+		fmt.Fprintf(w, "| %s |\n", strings.Join(section.Columns, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(section.Columns)))
+		for _, row := range section.Rows {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SoundCanvas settings</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #888; padding: 0.2em 0.6em; text-align: right; }
+</style>
+</head>
+<body>
+{{range .}}<h2>{{.Title}}</h2>
+<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+func writeHTMLReport(w io.Writer, sections []reportSection) error {
+	return htmlReportTemplate.Execute(w, sections)
+}
+
+var reportFormats = map[string]func(io.Writer, []reportSection) error{
+	"text":     writeTextReport,
+	"markdown": writeMarkdownReport,
+	"html":     writeHTMLReport,
+}
+
+type reportCommand struct {
+	settingsFile string
+	format       string
+	all          bool
+}
+
+func (*reportCommand) Name() string { return "report" }
+func (*reportCommand) Synopsis() string {
+	return "print a human-readable summary of the device settings"
+}
+func (*reportCommand) Usage() string { return "" }
+
+func (c *reportCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.settingsFile, "settings", "", "settings file to summarize; if not given, the current device state is read")
+	f.StringVar(&c.format, "format", "text", "output format: text, markdown or html")
+	f.BoolVar(&c.all, "all", false, "include all registers rather than just the commonly used ones")
+}
+
+func (c *reportCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	write, ok := reportFormats[c.format]
+	if !ok {
+		log.Printf("unknown report format %q", c.format)
+		return subcommands.ExitUsageError
+	}
+	s, err := currentSettings(c.settingsFile, true)
+	if err != nil {
+		log.Printf("failed to get settings: %v", err)
+		return subcommands.ExitFailure
+	}
+	if err := write(os.Stdout, reportSections(s, c.all)); err != nil {
+		log.Printf("failed to write report: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// systemModes maps the names accepted by reset-gs to system modes.
+var systemModes = map[string]sc55.SystemMode{
+	"mode-1": sc55.SystemModeDouble,
+	"double": sc55.SystemModeDouble,
+	"mode-2": sc55.SystemModeSingle,
+	"single": sc55.SystemModeSingle,
+}
+
+type resetGSCommand struct {
+	checkpoint bool
+}
+
+func (*resetGSCommand) Name() string { return "reset-gs" }
+func (*resetGSCommand) Synopsis() string {
+	return "Reset the SoundCanvas into GS mode, optionally choosing the system mode (mode-1 or mode-2)"
+}
+func (*resetGSCommand) Usage() string {
+	return `reset-gs [flags] [mode-1 | mode-2]:
+Sends a GS reset. If a system mode is given (mode-1 or "double" for double
+module mode, mode-2 or "single" for single module mode), a system mode set
+selecting it is sent first, followed by the GS reset once the device has
+had time to reinitialize. The original SC-55 has no system mode and
+ignores the mode set, so it just gets the GS reset.
+`
+}
+
+func (c *resetGSCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setCheckpointFlag(f, &c.checkpoint, false)
+}
+
+func (c *resetGSCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	msgs := [][]byte{}
+	switch len(f.Args()) {
+	case 0:
+	case 1:
+		mode, ok := systemModes[f.Args()[0]]
+		if !ok {
+			log.Printf("unknown system mode %q: want mode-1 (double module) or mode-2 (single module)", f.Args()[0])
+			return subcommands.ExitUsageError
+		}
+		msgs = append(msgs, sc55.ModeSet(deviceID(), mode))
+	default:
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	msgs = append(msgs, sc55.ResetGS(deviceID()))
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	for i, msg := range msgs {
+		if i > 0 {
+			time.Sleep(gsResetDelay)
+		}
+		if err := writeSysEx(out, msg); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
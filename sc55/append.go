@@ -0,0 +1,85 @@
+package sc55
+
+import "fmt"
+
+// The Append functions are variants of the functions that build messages
+// which append the message to a caller-provided slice instead of
+// allocating a new one, for programs that send messages at a high rate
+// (eg. animating the display or proxying a live performance). If dst has
+// enough capacity they don't allocate at all; a buffer can be reused for
+// each message by passing buf[:0].
+
+// appendHeader appends the start of a Roland SysEx message, up to and
+// including the command byte.
+func appendHeader(dst []byte, device DeviceID, modelID byte, cmd Command) []byte {
+	return append(dst, SysExStart, ManufacturerID, byte(device), modelID, byte(cmd))
+}
+
+// appendInt appends an address or size in the given number of bytes, most
+// significant first.
+func appendInt(dst []byte, val, width int) []byte {
+	for i := 0; i < width; i++ {
+		dst = append(dst, byte((val>>(8*(width-i-1)))&0xff))
+	}
+	return dst
+}
+
+// appendTrailer appends the checksum of the message body, which starts at
+// dst[start], and the end of the message.
+func appendTrailer(dst []byte, start int) []byte {
+	return append(dst, Checksum(dst[start:]), SysExEnd)
+}
+
+// AppendDataSet appends the DT1 command returned by DataSet to dst and
+// returns the extended slice.
+func AppendDataSet(dst []byte, device DeviceID, addr int, data ...byte) []byte {
+	dst = appendHeader(dst, device, modelIDForAddress(addr), CommandDT1)
+	start := len(dst)
+	dst = appendInt(dst, addr, 3)
+	dst = append(dst, data...)
+	return appendTrailer(dst, start)
+}
+
+// AppendDataGet appends the RQ1 command returned by DataGet to dst and
+// returns the extended slice.
+func AppendDataGet(dst []byte, device DeviceID, addr, size int) []byte {
+	dst = appendHeader(dst, device, modelIDForAddress(addr), CommandRQ1)
+	start := len(dst)
+	dst = appendInt(dst, addr, 3)
+	dst = appendInt(dst, size, 3)
+	return appendTrailer(dst, start)
+}
+
+// AppendSet appends the DT1 command returned by Set to dst and returns the
+// extended slice. On error, dst is returned unchanged.
+func (r *Register) AppendSet(dst []byte, device DeviceID, value int) ([]byte, error) {
+	orig := len(dst)
+	dst = appendHeader(dst, device, modelIDForAddress(r.Address), CommandDT1)
+	start := len(dst)
+	dst = appendInt(dst, r.Address, 3)
+	dst, err := r.appendEncoded(dst, Clamp(value+r.Zero, r.Min, r.Max))
+	if err != nil {
+		return dst[:orig], fmt.Errorf("register %q: %v", r.Name(), err)
+	}
+	return appendTrailer(dst, start), nil
+}
+
+// AppendBytes appends the complete message, as returned by Bytes, to dst
+// and returns the extended slice.
+func (m *Message) AppendBytes(dst []byte) []byte {
+	dst = append(dst, SysExStart, ManufacturerID, byte(m.device))
+	if m.modelID == nil {
+		dst = append(dst, modelIDForAddress(m.address))
+	} else {
+		dst = append(dst, m.modelID...)
+	}
+	dst = append(dst, byte(m.command))
+	start := len(dst)
+	dst = appendInt(dst, m.address, m.width)
+	if m.sizeSet {
+		dst = appendInt(dst, m.size, m.width)
+	} else {
+		dst = append(dst, m.data...)
+	}
+	return appendTrailer(dst, start)
+}
@@ -0,0 +1,64 @@
+package sc55
+
+import "fmt"
+
+// Block is a contiguous range of SC-55 memory that can be read with a
+// single RQ1 command.
+type Block struct {
+	Address, Size int
+}
+
+// Blocks returns a set of blocks that together cover all of the given
+// registers, so that they can be read in a handful of requests rather than
+// one per register. Registers are only grouped together if they share the
+// same 256-byte page, since addresses are 7 bits per byte on the wire and
+// so don't carry over cleanly between pages.
+func Blocks(regs []*Register) []Block {
+	pages := map[int]*Block{}
+	order := []int{}
+	for _, r := range regs {
+		page := r.Address >> 8
+		b, ok := pages[page]
+		if !ok {
+			pages[page] = &Block{r.Address, r.Size}
+			order = append(order, page)
+			continue
+		}
+		start, end := b.Address, b.Address+b.Size
+		if r.Address < start {
+			start = r.Address
+		}
+		if r.Address+r.Size > end {
+			end = r.Address + r.Size
+		}
+		b.Address, b.Size = start, end-start
+	}
+	result := []Block{}
+	for _, page := range order {
+		result = append(result, *pages[page])
+	}
+	return result
+}
+
+// Get returns an SC-55 RQ1 command that requests the contents of the block.
+func (b Block) Get(device DeviceID) []byte {
+	return DataGet(device, b.Address, b.Size)
+}
+
+// Contains returns true if the given register lies entirely within the block.
+func (b Block) Contains(r *Register) bool {
+	return r.Address >= b.Address && r.Address+r.Size <= b.Address+b.Size
+}
+
+// Decode extracts the value of a register from the contents of a block
+// that contains it.
+func (b Block) Decode(r *Register, data []byte) (int, error) {
+	if !b.Contains(r) {
+		return 0, fmt.Errorf("register %q at %x is not in block %x-%x", r.Name(), r.Address, b.Address, b.Address+b.Size-1)
+	}
+	if len(data) != b.Size {
+		return 0, fmt.Errorf("wrong block size: want %d bytes, got %d", b.Size, len(data))
+	}
+	offset := r.Address - b.Address
+	return r.Decode(data[offset : offset+r.Size])
+}
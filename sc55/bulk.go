@@ -0,0 +1,155 @@
+package sc55
+
+import "fmt"
+
+const (
+	// AddrBulkDump is the start of the bulk dump address space. Reading it
+	// returns the system and part parameters in a packed, nibblized form
+	// rather than the register layout at 0x40xxxx.
+	AddrBulkDump = 0x480000
+
+	// bulkDumpAllSize is the size requested for a dump of everything, as
+	// it appears in the RQ1 message.
+	bulkDumpAllSize = 0x001d10
+)
+
+// sevenBitOffset converts an address in the bulk dump space to a byte
+// offset. Unlike the other address spaces, a bulk dump is sent as a run of
+// messages whose addresses carry over from one 128-byte page to the next.
+func sevenBitOffset(addr int) int {
+	return linearAddress(addr) - linearAddress(AddrBulkDump)
+}
+
+// BulkDumpLength is the number of bytes of data in a complete bulk dump.
+var BulkDumpLength = linearAddress(bulkDumpAllSize)
+
+// BulkDumpRequest returns an RQ1 command that requests a bulk dump of all
+// system and part parameters. The device replies with a series of DT1
+// messages, which can be collected with a BulkDump.
+func BulkDumpRequest(device DeviceID) []byte {
+	return DataGet(device, AddrBulkDump, bulkDumpAllSize)
+}
+
+// BulkDump reassembles the reply to a BulkDumpRequest.
+type BulkDump struct {
+	data      []byte
+	received  []bool
+	remaining int
+	messages  [][]byte
+}
+
+// NewBulkDump returns an empty BulkDump, ready to receive messages.
+func NewBulkDump() *BulkDump {
+	return &BulkDump{
+		data:      make([]byte, BulkDumpLength),
+		received:  make([]bool, BulkDumpLength),
+		remaining: BulkDumpLength,
+	}
+}
+
+// Add adds a message from the reply to the dump. It returns false if the
+// message is not a DT1 from the given device (see DeviceID.Matches) to the
+// bulk dump space, in which case the message is ignored.
+func (d *BulkDump) Add(device DeviceID, msg []byte) (bool, error) {
+	dev, addr, data, err := UnmarshalSet(msg)
+	if err != nil || !dev.Matches(device) || addr&0xff0000 != AddrBulkDump {
+		return false, nil
+	}
+	start := sevenBitOffset(addr)
+	if start+len(data) > BulkDumpLength {
+		return false, fmt.Errorf("bulk dump message at %06x runs past the end of the dump", addr)
+	}
+	for i, v := range data {
+		if !d.received[start+i] {
+			d.received[start+i] = true
+			d.remaining--
+		}
+		d.data[start+i] = v
+	}
+	d.messages = append(d.messages, msg)
+	return true, nil
+}
+
+// Complete returns true once every byte of the dump has been received.
+func (d *BulkDump) Complete() bool {
+	return d.remaining == 0
+}
+
+// Data returns the raw contents of the dump, in bulk dump address order.
+func (d *BulkDump) Data() []byte {
+	return d.data
+}
+
+// Messages returns the messages that made up the dump, in the order they
+// were received. Sending them back to the device restores its state.
+func (d *BulkDump) Messages() [][]byte {
+	return d.messages
+}
+
+// bulkDumpSection is a run of register memory that is included in a bulk
+// dump.
+type bulkDumpSection struct {
+	// offset is the position of the section within the dump data.
+	offset int
+	// addr and size are the register memory that the section holds.
+	addr, size int
+}
+
+// bulkDumpSections is the layout of a bulk dump. Each byte of register
+// memory is sent as two bytes holding its high and low nibbles, so every
+// section takes up twice its size in the dump. The system section (the
+// master settings, then the patch common block) comes first, followed by
+// each part's block in memory order; that is, part 10 first.
+var bulkDumpSections = func() []bulkDumpSection {
+	result := []bulkDumpSection{
+		{0x0000, 0x400000, 0x08},
+		{0x0010, 0x400100, 0x40},
+	}
+	for i := 0; i < 16; i++ {
+		result = append(result, bulkDumpSection{0x0090 + i*0xe0, 0x401000 + i*0x100, 0x70})
+	}
+	return result
+}()
+
+// bulkDumpSectionFor returns the section holding the given register, if any.
+func bulkDumpSectionFor(r *Register) (bulkDumpSection, bool) {
+	for _, s := range bulkDumpSections {
+		if r.Address >= s.addr && r.Address+r.Size <= s.addr+s.size {
+			return s, true
+		}
+	}
+	return bulkDumpSection{}, false
+}
+
+// Values decodes the dump into the values of the registers on the given
+// registry's model that it holds. Registers that lie outside the dumped
+// memory, such as the port B parts of 32-part models, are left out. An
+// error is returned if the dump is incomplete or holds a value that isn't
+// valid for its register.
+func (d *BulkDump) Values(reg *Registry) (map[*Register]int, error) {
+	if !d.Complete() {
+		return nil, fmt.Errorf("bulk dump is incomplete: %d bytes missing", d.remaining)
+	}
+	values := map[*Register]int{}
+	for _, r := range reg.AllRegisters() {
+		s, ok := bulkDumpSectionFor(r)
+		if !ok {
+			continue
+		}
+		start := s.offset + (r.Address-s.addr)*2
+		payload := make([]byte, r.Size)
+		for i := range payload {
+			hi, lo := d.data[start+i*2], d.data[start+i*2+1]
+			if hi > 0x0f || lo > 0x0f {
+				return nil, fmt.Errorf("register %q: invalid bulk dump data % x at offset %d", r.Name(), []byte{hi, lo}, start+i*2)
+			}
+			payload[i] = hi<<4 | lo
+		}
+		v, err := r.Decode(payload)
+		if err != nil {
+			return nil, fmt.Errorf("register %q: %v", r.Name(), err)
+		}
+		values[r] = v
+	}
+	return values, nil
+}
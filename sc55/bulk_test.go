@@ -0,0 +1,66 @@
+package sc55
+
+import "testing"
+
+func TestBulkDumpValues(t *testing.T) {
+	last := bulkDumpSections[len(bulkDumpSections)-1]
+	if got := last.offset + last.size*2; got != BulkDumpLength {
+		t.Fatalf("bulk dump sections end at %d, want %d", got, BulkDumpLength)
+	}
+	reg := NewRegistry(ModelSC55)
+	want := map[string]int{
+		"master-volume":          100,
+		"master-tune":            -20,
+		"master-key-shift":       3,
+		"reverb-level":           64,
+		"chorus-level":           0,
+		"part-1.part-level":      90,
+		"part-10.part-level":     80,
+		"part-16.pan-pot":        -10,
+		"part-10.tone-number-cc": 0x0100,
+		"part-1.cc-1-controller": 12,
+	}
+	// Build a dump holding the values, with every other register at its
+	// minimum.
+	d := NewBulkDump()
+	for _, r := range reg.AllRegisters() {
+		s, ok := bulkDumpSectionFor(r)
+		if !ok {
+			continue
+		}
+		v, ok := want[r.Name()]
+		if !ok {
+			v = r.Min - r.Zero
+		}
+		payload, err := r.encode(v + r.Zero)
+		if err != nil {
+			t.Fatalf("%s: encode(%d) failed: %v", r.Name(), v, err)
+		}
+		start := s.offset + (r.Address-s.addr)*2
+		for i, b := range payload {
+			d.data[start+i*2], d.data[start+i*2+1] = b>>4, b&0x0f
+		}
+	}
+	if _, err := d.Values(reg); err == nil {
+		t.Errorf("Values() of incomplete dump succeeded, want error")
+	}
+	for _, msg := range SplitDataSet(0x10, AddrBulkDump, append([]byte{}, d.data...)...) {
+		if ok, err := d.Add(0x10, msg); !ok || err != nil {
+			t.Fatalf("Add(% x) = %v, %v", msg, ok, err)
+		}
+	}
+	values, err := d.Values(reg)
+	if err != nil {
+		t.Fatalf("Values() failed: %v", err)
+	}
+	for name, v := range want {
+		r, ok := reg.RegisterByName(name)
+		if !ok {
+			t.Errorf("no register %q", name)
+			continue
+		}
+		if got, ok := values[r]; !ok || got != v {
+			t.Errorf("%s: got %d, %v, want %d", name, got, ok, v)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package sc55
+
+import "fmt"
+
+// registerUnits holds the units of registers whose values are a physical
+// quantity, as shown by Units.
+var registerUnits map[*Register]string
+
+// Units returns the units of the register's values (eg. "semitones"), or
+// the empty string if they are a plain level or setting.
+func (r *Register) Units() string {
+	return registerUnits[r]
+}
+
+func addUnits() {
+	registerUnits[&MasterTune] = "0.1 cents"
+	registerUnits[&MasterKeyShift] = "semitones"
+	registerUnits[&EQLowGain] = "dB"
+	registerUnits[&EQHighGain] = "dB"
+	for i := range voiceReserve {
+		registerUnits[&voiceReserve[i]] = "voices"
+	}
+}
+
+// RegisterInfo describes a register, for generating documentation or user
+// interfaces from the register tables.
+type RegisterInfo struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	Size    int    `json:"size"`
+	// Min and Max are the range of values that can be passed to Set,
+	// with the zero offset applied.
+	Min int `json:"min"`
+	Max int `json:"max"`
+	// Zero is the raw value that Set writes for a value of zero.
+	Zero  int    `json:"zero"`
+	Units string `json:"units,omitempty"`
+	// Controller is the MIDI controller that changes the register, if
+	// any; see Controller.
+	Controller *int     `json:"controller,omitempty"`
+	Important  bool     `json:"important"`
+	Model      string   `json:"model"`
+	Group      string   `json:"group"`
+	Tags       []string `json:"tags"`
+	Values     []string `json:"values,omitempty"`
+	Encoding   string   `json:"encoding"`
+}
+
+// Info returns a description of the register.
+func (r *Register) Info() RegisterInfo {
+	min, max := r.Range()
+	tags := []string{}
+	for _, t := range r.Tags() {
+		tags = append(tags, string(t))
+	}
+	encoding := "bytes"
+	if r.Encoding() == EncodingNibbles {
+		encoding = "nibbles"
+	}
+	info := RegisterInfo{
+		Name:      r.Name(),
+		Address:   fmt.Sprintf("%06x", r.Address),
+		Size:      r.Size,
+		Min:       min,
+		Max:       max,
+		Zero:      r.Zero,
+		Units:     r.Units(),
+		Important: r.Important(),
+		Model:     r.Model().String(),
+		Group:     string(r.Group()),
+		Tags:      tags,
+		Values:    r.ValueNames(),
+		Encoding:  encoding,
+	}
+	if cc, ok := r.Controller(); ok {
+		info.Controller = &cc
+	}
+	return info
+}
+
+// Catalog returns descriptions of all the registers on the current model,
+// in address order.
+func Catalog() []RegisterInfo {
+	return DefaultRegistry().Catalog()
+}
@@ -0,0 +1,51 @@
+package sc55
+
+import "fmt"
+
+// registerControllers holds the MIDI controller number of part registers
+// that can also be changed with a control change message on the part's
+// channel.
+var registerControllers map[*Register]int
+
+// Controller returns the number of the MIDI controller that changes the
+// register, and true, or 0, false if it can only be changed with SysEx.
+func (r *Register) Controller() (int, bool) {
+	cc, ok := registerControllers[r]
+	return cc, ok
+}
+
+// ControlChange returns a control change message that sets the register
+// to the given value (clamped, with the zero offset applied as for Set)
+// for a part receiving on the given MIDI channel (1-16). Unlike a DT1 it
+// takes effect without interrupting notes that are playing, so it can be
+// sent repeatedly to change a value smoothly.
+func (r *Register) ControlChange(channel, value int) ([]byte, error) {
+	cc, ok := r.Controller()
+	switch {
+	case !ok:
+		return nil, fmt.Errorf("register %q has no controller equivalent", r.Name())
+	case channel < 1 || channel > 16:
+		return nil, fmt.Errorf("invalid channel %d, want 1 <= x <= 16", channel)
+	}
+	v := Clamp(Clamp(value+r.Zero, r.Min, r.Max), 0x00, 0x7f)
+	return []byte{0xb0 | byte(channel-1), byte(cc), byte(v)}, nil
+}
+
+// Ramp returns the values to step through to go from one value to another
+// in the given number of steps, ending with to. Repeated values are left
+// out, so fewer steps may be returned if the values are close together.
+func Ramp(from, to, steps int) []int {
+	if steps < 1 {
+		steps = 1
+	}
+	result := []int{}
+	last := from
+	for i := 1; i <= steps; i++ {
+		v := from + (to-from)*i/steps
+		if v != last {
+			result = append(result, v)
+			last = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package sc55
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The display message is sent as DT1 data, so only 7-bit character codes
+// can be used. The LCD's character set is JIS X 0201 (see DisplayCharset),
+// so characters outside it have to be transliterated first or they turn
+// into garbage on the LCD. The LCD's katakana have the top bit set, and
+// there is no documented way to send them, so they can't be shown.
+
+// DisplayCharset maps the LCD's character codes to the characters they
+// show. Like other Japanese LCD character sets it follows JIS X 0201
+// rather than ASCII, so code 0x5c is a yen sign, codes 0x7e and 0x7f are
+// arrows, and codes 0xa1 to 0xdf are half-width katakana. Codes with no
+// visible character map to zero.
+var DisplayCharset [256]rune
+
+// displayCodes is the inverse of DisplayCharset.
+var displayCodes = map[rune]byte{}
+
+var accentedLetters = map[string]string{
+	"a": "àáâãäåāăą", "A": "ÀÁÂÃÄÅĀĂĄ",
+	"c": "çćĉċč", "C": "ÇĆĈĊČ",
+	"d": "ďđð", "D": "ĎĐÐ",
+	"e": "èéêëēĕėęě", "E": "ÈÉÊËĒĔĖĘĚ",
+	"g": "ĝğġģ", "G": "ĜĞĠĢ",
+	"h": "ĥħ", "H": "ĤĦ",
+	"i": "ìíîïĩīĭįı", "I": "ÌÍÎÏĨĪĬĮİ",
+	"j": "ĵ", "J": "Ĵ",
+	"k": "ķ", "K": "Ķ",
+	"l": "ĺļľŀł", "L": "ĹĻĽĿŁ",
+	"n": "ñńņňŉ", "N": "ÑŃŅŇ",
+	"o": "òóôõöøōŏő", "O": "ÒÓÔÕÖØŌŎŐ",
+	"r": "ŕŗř", "R": "ŔŖŘ",
+	"s": "śŝşš", "S": "ŚŜŞŠ",
+	"t": "ţťŧ", "T": "ŢŤŦ",
+	"u": "ùúûüũūŭůűų", "U": "ÙÚÛÜŨŪŬŮŰŲ",
+	"w": "ŵ", "W": "Ŵ",
+	"y": "ýÿŷ", "Y": "ÝŸŶ",
+	"z": "źżž", "Z": "ŹŻŽ",
+	"ss": "ß", "ae": "æ", "AE": "Æ", "oe": "œ", "OE": "Œ",
+	"th": "þ", "TH": "Þ",
+	"'": "‘’‚′`´", "\"": "“”„″", "...": "…",
+	"*": "•·", "x": "×", "/": "÷\\", "<<": "«", ">>": "»", "-": "–—‐−~",
+	".": "。・", ",": "、", "[": "「『", "]": "」』", " ": "　 ",
+}
+
+var transliterations = map[rune]string{}
+
+func init() {
+	for c := 0x20; c < 0x7f; c++ {
+		DisplayCharset[c] = rune(c)
+	}
+	DisplayCharset[0x5c] = '¥'
+	DisplayCharset[0x7e] = '→'
+	DisplayCharset[0x7f] = '←'
+	for c := 0xa1; c <= 0xdf; c++ {
+		// U+FF61 to U+FF9F are in the same order as JIS X 0201.
+		DisplayCharset[c] = rune(0xff61 + c - 0xa1)
+	}
+	for code, c := range DisplayCharset {
+		if c != 0 {
+			displayCodes[c] = byte(code)
+		}
+	}
+	for ascii, chars := range accentedLetters {
+		for _, c := range chars {
+			transliterations[c] = ascii
+		}
+	}
+}
+
+// Transliterate converts a UTF-8 string to the characters that can be
+// shown on the SC-55 display (see DisplayCharset). Accented Latin letters
+// lose their accents, typographic punctuation and full-width forms are
+// replaced by their ASCII equivalents, and any other character that can't
+// be shown, including kana, is replaced with "?".
+func Transliterate(s string) string {
+	out := []string{}
+	for _, c := range s {
+		switch {
+		case displayCodes[c] != 0 && displayCodes[c] < 0x80:
+			out = append(out, string(c))
+		case c >= 0xff01 && c <= 0xff5e:
+			// Full-width ASCII
+			out = append(out, Transliterate(string(c-0xfee0)))
+		case transliterations[c] != "":
+			out = append(out, transliterations[c])
+		default:
+			out = append(out, "?")
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// EncodeDisplay transliterates a UTF-8 string and returns the LCD character
+// codes for it (see DisplayCharset).
+func EncodeDisplay(s string) []byte {
+	result := []byte{}
+	for _, c := range Transliterate(s) {
+		result = append(result, displayCodes[c])
+	}
+	return result
+}
+
+// DecodeDisplayMessage converts the data of a display message DT1 back to a
+// string. Codes that have no visible character are decoded as U+FFFD.
+func DecodeDisplayMessage(data []byte) string {
+	var sb strings.Builder
+	for _, b := range data {
+		if b >= 0x80 || DisplayCharset[b] == 0 {
+			sb.WriteRune(utf8.RuneError)
+			continue
+		}
+		sb.WriteRune(DisplayCharset[b])
+	}
+	return sb.String()
+}
@@ -0,0 +1,54 @@
+package sc55
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello", "Hello"},
+		{"Café crème", "Cafe creme"},
+		{"“quoted” – dash", "\"quoted\" - dash"},
+		{"Ｆｕｌｌ", "Full"},
+		{"C:\\path~", "C:/path-"},
+		{"¥100 →", "¥100 →"},
+		{"カタカナ", "????"},
+		{"ｶﾀｶﾅ", "????"},
+		{"「ガ」。", "[?]."},
+		{"漢字", "??"},
+	}
+	for _, tt := range tests {
+		if got := Transliterate(tt.in); got != tt.want {
+			t.Errorf("Transliterate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDisplayMessage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []byte
+	}{
+		{"AB", []byte{'A', 'B'}},
+		{"¥→", []byte{0x5c, 0x7e}},
+		{"Aア", []byte{'A', '?'}},
+		{strings.Repeat("x", 40), bytes.Repeat([]byte{'x'}, 31)},
+	}
+	for _, tt := range tests {
+		_, _, data, err := UnmarshalSet(DisplayMessage(0x10, tt.in))
+		if err != nil {
+			t.Errorf("UnmarshalSet(DisplayMessage(%q)) failed: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.want) {
+			t.Errorf("DisplayMessage(%q) data = % x, want % x", tt.in, data, tt.want)
+		}
+	}
+	if got, want := DecodeDisplayMessage([]byte{'A', 0x5c, 0xb1}), "A¥\ufffd"; got != want {
+		t.Errorf("DecodeDisplayMessage() = %q, want %q", got, want)
+	}
+}
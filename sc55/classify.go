@@ -0,0 +1,103 @@
+package sc55
+
+// MessageType is the kind of a SysEx message, as returned by Classify.
+type MessageType int
+
+const (
+	// MessageUnknown is anything not recognized as one of the other types.
+	MessageUnknown MessageType = iota
+	// MessageDT1 is a DT1 that writes to memory, other than the more
+	// specific types below.
+	MessageDT1
+	// MessageRQ1 is an RQ1 requesting the contents of memory.
+	MessageRQ1
+	// MessageDisplay is a DT1 to the front panel display.
+	MessageDisplay
+	// MessageGSReset is a GS reset (a DT1 to AddrModeSet).
+	MessageGSReset
+	// MessageSystemModeSet is a system mode set (a DT1 to
+	// AddrSystemModeSet).
+	MessageSystemModeSet
+	// MessageGMReset is a General MIDI reset.
+	MessageGMReset
+	// MessageIdentityReply is a universal identity reply from a Roland
+	// device, sent in response to an identity request.
+	MessageIdentityReply
+	// MessageIdentityRequest is a universal identity request.
+	MessageIdentityRequest
+	// MessageGM2On is a universal GM2 System On message.
+	MessageGM2On
+	// MessageMasterVolume is a universal realtime Master Volume message.
+	MessageMasterVolume
+)
+
+var messageTypeNames = map[MessageType]string{
+	MessageUnknown:         "unknown",
+	MessageDT1:             "DT1",
+	MessageRQ1:             "RQ1",
+	MessageDisplay:         "display",
+	MessageGSReset:         "GS reset",
+	MessageSystemModeSet:   "system mode set",
+	MessageGMReset:         "GM reset",
+	MessageIdentityReply:   "identity reply",
+	MessageIdentityRequest: "identity request",
+	MessageGM2On:           "GM2 on",
+	MessageMasterVolume:    "master volume",
+}
+
+func (t MessageType) String() string {
+	return messageTypeNames[t]
+}
+
+// Classify returns the type of the given SysEx message. Only the framing
+// is checked, not the checksum.
+func Classify(msg []byte) MessageType {
+	if len(msg) < 6 || msg[0] != SysExStart || msg[len(msg)-1] != SysExEnd {
+		return MessageUnknown
+	}
+	// The universal non-realtime GM reset, F0 7E <dev> 09 01 F7:
+	if msg[1] == 0x7e && msg[3] == ModelIDGM && msg[4] == 0x01 {
+		return MessageGMReset
+	}
+	// The universal non-realtime GM2 System On, F0 7E <dev> 09 03 F7:
+	if msg[1] == 0x7e && msg[3] == ModelIDGM && msg[4] == 0x03 {
+		return MessageGM2On
+	}
+	// The universal realtime Master Volume, F0 7F <dev> 04 01 <lsb> <msb> F7:
+	if msg[1] == 0x7f && msg[3] == 0x04 && msg[4] == 0x01 && len(msg) == 8 {
+		return MessageMasterVolume
+	}
+	// The universal non-realtime identity request, F0 7E <dev> 06 01 F7:
+	if msg[1] == 0x7e && msg[3] == 0x06 && msg[4] == 0x01 {
+		return MessageIdentityRequest
+	}
+	// The universal non-realtime identity reply, F0 7E <dev> 06 02 41 ...:
+	if msg[1] == 0x7e && msg[3] == 0x06 && msg[4] == 0x02 && msg[5] == ManufacturerID {
+		return MessageIdentityReply
+	}
+	if msg[1] != ManufacturerID {
+		return MessageUnknown
+	}
+	if msg[3] == ModelIDGM && msg[4] == 0x01 {
+		return MessageGMReset
+	}
+	if (msg[3] != ModelIDGS && msg[3] != ModelIDDisplay) || len(msg) < 10 {
+		return MessageUnknown
+	}
+	addr := unmarshalInt24(msg[5:8])
+	switch Command(msg[4]) {
+	case CommandRQ1:
+		return MessageRQ1
+	case CommandDT1:
+		switch {
+		case msg[3] == ModelIDDisplay:
+			return MessageDisplay
+		case addr == AddrModeSet:
+			return MessageGSReset
+		case addr == AddrSystemModeSet:
+			return MessageSystemModeSet
+		}
+		return MessageDT1
+	}
+	return MessageUnknown
+}
@@ -0,0 +1,321 @@
+package sc55
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned (wrapped) by Device methods when the SoundCanvas
+// doesn't reply in time, even after retrying.
+var ErrTimeout = errors.New("timeout waiting for reply")
+
+// Input is the MIDI input that a Device receives replies on.
+type Input interface {
+	// ReadSysEx returns the next SysEx message received, or nil if
+	// nothing has arrived yet. It must not block.
+	ReadSysEx() ([]byte, error)
+}
+
+// Output is the MIDI output that a Device sends messages on.
+type Output interface {
+	WriteSysEx(msg []byte) error
+}
+
+// Device is a SoundCanvas connected over MIDI. It takes care of sending
+// requests and waiting for the matching replies, so that registers can be
+// read and written with single calls.
+type Device struct {
+	in  Input
+	out Output
+	id  DeviceID
+
+	// Timeout is how long to wait for each reply.
+	Timeout time.Duration
+	// Retries is the number of times a request is sent again if no reply
+	// is received before the timeout.
+	Retries int
+	// AdaptiveTimeout shortens the wait for the start of a reply once
+	// some replies have been received, to a few times the slowest seen
+	// so far (but never more than Timeout). Each retry waits twice as
+	// long as the last. This speeds up reading memory that the device
+	// doesn't implement, which it never replies to, at the risk of
+	// giving up early on a device that is occasionally slow.
+	AdaptiveTimeout bool
+	// WriteDelay is the time to wait after every message that changes
+	// the device state, so that the device keeps up.
+	WriteDelay time.Duration
+	// MinPollInterval and PollInterval are the shortest and longest
+	// intervals between checks of the input while waiting. Checks start
+	// at the shortest interval and back off to the longest while nothing
+	// arrives.
+	MinPollInterval, PollInterval time.Duration
+	// Strict makes SetRegister and SetRegisters return an error for
+	// values out of range, instead of clamping them.
+	Strict bool
+
+	stats DeviceStats
+}
+
+// DeviceStats counts the requests made to a Device and how quickly they
+// were answered.
+type DeviceStats struct {
+	// Requests is the number of requests made, and Timeouts the number
+	// of attempts at them that timed out.
+	Requests, Timeouts int
+	// Replies is the number of attempts that got a reply. MinLatency
+	// and MaxLatency are the shortest and longest times from sending a
+	// request to the arrival of the start of its reply.
+	Replies                int
+	MinLatency, MaxLatency time.Duration
+	TotalLatency           time.Duration
+}
+
+// MeanLatency returns the average time to the start of a reply.
+func (s DeviceStats) MeanLatency() time.Duration {
+	if s.Replies == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Replies)
+}
+
+func (s *DeviceStats) observe(latency time.Duration) {
+	if s.Replies == 0 || latency < s.MinLatency {
+		s.MinLatency = latency
+	}
+	if latency > s.MaxLatency {
+		s.MaxLatency = latency
+	}
+	s.Replies++
+	s.TotalLatency += latency
+}
+
+const (
+	// adaptiveTimeoutFactor is how many times the slowest reply seen so
+	// far to wait for a reply with AdaptiveTimeout.
+	adaptiveTimeoutFactor = 4
+	// minAdaptiveTimeout is the shortest wait with AdaptiveTimeout,
+	// since scheduling delays on the host can exceed the latency of the
+	// device itself.
+	minAdaptiveTimeout = 10 * time.Millisecond
+)
+
+// NewDevice returns a Device that sends messages to the SoundCanvas with
+// the given device ID on out, and receives replies from it on in.
+func NewDevice(in Input, out Output, id DeviceID) *Device {
+	return &Device{
+		in:              in,
+		out:             out,
+		id:              id,
+		Timeout:         100 * time.Millisecond,
+		MinPollInterval: 50 * time.Microsecond,
+		PollInterval:    time.Millisecond,
+	}
+}
+
+// Stats returns statistics about the requests made so far.
+func (d *Device) Stats() DeviceStats {
+	return d.stats
+}
+
+// replyTimeout returns how long to wait for the start of a reply to the
+// given attempt (0 for the first) at a request.
+func (d *Device) replyTimeout(attempt int) time.Duration {
+	if !d.AdaptiveTimeout || d.stats.Replies == 0 {
+		return d.Timeout
+	}
+	t := d.stats.MaxLatency * adaptiveTimeoutFactor
+	if t < minAdaptiveTimeout {
+		t = minAdaptiveTimeout
+	}
+	for i := 0; i < attempt && t < d.Timeout; i++ {
+		t *= 2
+	}
+	if t > d.Timeout {
+		t = d.Timeout
+	}
+	return t
+}
+
+// ID returns the device ID of the device.
+func (d *Device) ID() DeviceID {
+	return d.id
+}
+
+// Write sends a message to the device, followed by WriteDelay.
+func (d *Device) Write(msg []byte) error {
+	if err := d.out.WriteSysEx(msg); err != nil {
+		return err
+	}
+	time.Sleep(d.WriteDelay)
+	return nil
+}
+
+// request sends the given request and passes every reply to handle until
+// it returns true. The request is sent again if nothing is received within
+// the timeout, up to Retries times. Once the start of a reply has arrived,
+// the rest of it is waited for for up to Timeout.
+func (d *Device) request(ctx context.Context, req []byte, handle func(reply []byte) bool) error {
+	d.stats.Requests++
+	for attempt := 0; attempt <= d.Retries; attempt++ {
+		if err := d.out.WriteSysEx(req); err != nil {
+			return err
+		}
+		sent := time.Now()
+		timeoutTime := sent.Add(d.replyTimeout(attempt))
+		replied := false
+		poll := d.MinPollInterval
+		if poll <= 0 {
+			poll = d.PollInterval
+		}
+		for {
+			now := time.Now()
+			if !now.Before(timeoutTime) {
+				break
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			reply, err := d.in.ReadSysEx()
+			if err != nil {
+				return err
+			}
+			if len(reply) == 0 {
+				if wait := timeoutTime.Sub(now); wait < poll {
+					poll = wait
+				}
+				time.Sleep(poll)
+				if poll *= 2; poll > d.PollInterval {
+					poll = d.PollInterval
+				}
+				continue
+			}
+			if d.MinPollInterval > 0 {
+				poll = d.MinPollInterval
+			}
+			if !replied {
+				replied = true
+				d.stats.observe(time.Since(sent))
+				timeoutTime = sent.Add(d.Timeout)
+			}
+			if handle(reply) {
+				return nil
+			}
+		}
+		d.stats.Timeouts++
+	}
+	return ErrTimeout
+}
+
+// QueryRegister reads the current value of a register.
+func (d *Device) QueryRegister(ctx context.Context, r *Register) (int, error) {
+	var value int
+	err := d.request(ctx, r.Get(d.id), func(reply []byte) bool {
+		dev, v, err := r.Unmarshal(reply)
+		if err != nil || !dev.Matches(d.id) {
+			return false
+		}
+		value = v
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("fetching register %q value: %w", r.Name(), err)
+	}
+	return value, nil
+}
+
+// QueryBlock reads the contents of a block of memory. The reply may be
+// split over several DT1 messages.
+func (d *Device) QueryBlock(ctx context.Context, b Block) ([]byte, error) {
+	data := make([]byte, b.Size)
+	received := make([]bool, b.Size)
+	remaining := b.Size
+	err := d.request(ctx, b.Get(d.id), func(reply []byte) bool {
+		dev, addr, payload, err := UnmarshalSet(reply)
+		if err != nil || !dev.Matches(d.id) {
+			return false
+		}
+		for i, v := range payload {
+			offset := addr + i - b.Address
+			if offset >= 0 && offset < b.Size && !received[offset] {
+				data[offset] = v
+				received[offset] = true
+				remaining--
+			}
+		}
+		return remaining == 0
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetching block at %x: %w", b.Address, err)
+	}
+	return data, nil
+}
+
+// QueryRegisters reads the values of the given registers, reading whole
+// blocks of memory at once rather than making a request per register.
+// Errors are reported per register.
+func (d *Device) QueryRegisters(ctx context.Context, regs []*Register) (map[*Register]int, map[*Register]error) {
+	values := make(map[*Register]int)
+	errs := make(map[*Register]error)
+	for _, b := range Blocks(regs) {
+		data, err := d.QueryBlock(ctx, b)
+		for _, r := range regs {
+			if !b.Contains(r) {
+				continue
+			}
+			if err != nil {
+				errs[r] = err
+				continue
+			}
+			if values[r], err = b.Decode(r, data); err != nil {
+				delete(values, r)
+				errs[r] = err
+			}
+		}
+	}
+	return values, errs
+}
+
+// SetRegister sets a register to the given value, which is clamped to the
+// register's range unless Strict is set.
+func (d *Device) SetRegister(ctx context.Context, r *Register, value int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d.Strict {
+		if err := r.CheckRange(value); err != nil {
+			return err
+		}
+	}
+	msg, err := r.Set(d.id, value)
+	if err != nil {
+		return err
+	}
+	return d.Write(msg)
+}
+
+// SetRegisters sets several registers at once, writing registers at
+// contiguous addresses together as SetMany does.
+func (d *Device) SetRegisters(ctx context.Context, values map[*Register]int) error {
+	if d.Strict {
+		for r, value := range values {
+			if err := r.CheckRange(value); err != nil {
+				return err
+			}
+		}
+	}
+	msgs, err := SetMany(d.id, values)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := d.Write(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package sc55
+
+import "fmt"
+
+// Names of the values of registers that select from a small set of
+// options rather than holding a level, as listed in the SC-55 manual.
+var (
+	reverbTypeNames = []string{"room1", "room2", "room3", "hall1", "hall2", "plate", "delay", "panning-delay"}
+	chorusTypeNames = []string{"chorus1", "chorus2", "chorus3", "chorus4", "feedback-chorus", "flanger", "short-delay", "short-delay-fb"}
+	switchNames     = []string{"off", "on"}
+	monoPolyNames   = []string{"mono", "poly"}
+	assignModeNames = []string{"single", "limited-multi", "full-multi"}
+	rhythmNames     = []string{"off", "map1", "map2"}
+	eqLowFreqNames  = []string{"200hz", "400hz"}
+	eqHighFreqNames = []string{"3khz", "6khz"}
+	valueNameTables = map[string][]string{
+		"switch":      switchNames,
+		"mono-poly":   monoPolyNames,
+		"assign-mode": assignModeNames,
+		"rhythm":      rhythmNames,
+	}
+	registerValueNames map[*Register][]string
+)
+
+// ValueNames returns the names of the register's values, indexed by value,
+// or nil if the register's values are plain numbers.
+func (r *Register) ValueNames() []string {
+	return registerValueNames[r]
+}
+
+// valueNamesForTag returns the table named in a "values" struct tag.
+func valueNamesForTag(table string) ([]string, error) {
+	if table == "" {
+		return nil, nil
+	}
+	names, ok := valueNameTables[table]
+	if !ok {
+		return nil, fmt.Errorf("unknown value names %q", table)
+	}
+	return names, nil
+}
+
+func addValueNames() {
+	registerValueNames[&ReverbMacro] = reverbTypeNames
+	registerValueNames[&ReverbCharacter] = reverbTypeNames
+	registerValueNames[&ChorusMacro] = chorusTypeNames
+	registerValueNames[&EQLowFreq] = eqLowFreqNames
+	registerValueNames[&EQHighFreq] = eqHighFreqNames
+	registerValueNames[&EFXSendEQSwitch] = switchNames
+}
@@ -0,0 +1,179 @@
+package sc55
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+const (
+	// glyphWidth and glyphHeight are the size of the characters drawn by
+	// DisplayText, in dots.
+	glyphWidth  = 4
+	glyphHeight = 7
+
+	// MaxDisplayText is the most characters that DisplayText can fit on
+	// the display at once.
+	MaxDisplayText = (16 + 1) / (glyphWidth + 1)
+)
+
+// font is the bitmap font used by DisplayText. Each row is a glyphWidth
+// bit pattern, with the most significant bit on the left. Lower case
+// letters are drawn as upper case.
+var font = map[rune][glyphHeight]byte{
+	' ': {0b0000, 0b0000, 0b0000, 0b0000, 0b0000, 0b0000, 0b0000},
+	'!': {0b0100, 0b0100, 0b0100, 0b0100, 0b0100, 0b0000, 0b0100},
+	'+': {0b0000, 0b0100, 0b0100, 0b1110, 0b0100, 0b0100, 0b0000},
+	'-': {0b0000, 0b0000, 0b0000, 0b1111, 0b0000, 0b0000, 0b0000},
+	'.': {0b0000, 0b0000, 0b0000, 0b0000, 0b0000, 0b0000, 0b0100},
+	'/': {0b0001, 0b0010, 0b0010, 0b0100, 0b0100, 0b1000, 0b1000},
+	':': {0b0000, 0b0100, 0b0100, 0b0000, 0b0100, 0b0100, 0b0000},
+	'=': {0b0000, 0b0000, 0b1111, 0b0000, 0b1111, 0b0000, 0b0000},
+	'?': {0b0110, 0b1001, 0b0001, 0b0010, 0b0100, 0b0000, 0b0100},
+	'0': {0b0110, 0b1001, 0b1011, 0b1101, 0b1001, 0b1001, 0b0110},
+	'1': {0b0010, 0b0110, 0b0010, 0b0010, 0b0010, 0b0010, 0b0111},
+	'2': {0b0110, 0b1001, 0b0001, 0b0010, 0b0100, 0b1000, 0b1111},
+	'3': {0b1110, 0b0001, 0b0001, 0b0110, 0b0001, 0b0001, 0b1110},
+	'4': {0b0010, 0b0110, 0b1010, 0b1010, 0b1111, 0b0010, 0b0010},
+	'5': {0b1111, 0b1000, 0b1110, 0b0001, 0b0001, 0b1001, 0b0110},
+	'6': {0b0110, 0b1000, 0b1000, 0b1110, 0b1001, 0b1001, 0b0110},
+	'7': {0b1111, 0b0001, 0b0010, 0b0010, 0b0100, 0b0100, 0b0100},
+	'8': {0b0110, 0b1001, 0b1001, 0b0110, 0b1001, 0b1001, 0b0110},
+	'9': {0b0110, 0b1001, 0b1001, 0b0111, 0b0001, 0b0001, 0b0110},
+	'A': {0b0110, 0b1001, 0b1001, 0b1111, 0b1001, 0b1001, 0b1001},
+	'B': {0b1110, 0b1001, 0b1001, 0b1110, 0b1001, 0b1001, 0b1110},
+	'C': {0b0110, 0b1001, 0b1000, 0b1000, 0b1000, 0b1001, 0b0110},
+	'D': {0b1110, 0b1001, 0b1001, 0b1001, 0b1001, 0b1001, 0b1110},
+	'E': {0b1111, 0b1000, 0b1000, 0b1110, 0b1000, 0b1000, 0b1111},
+	'F': {0b1111, 0b1000, 0b1000, 0b1110, 0b1000, 0b1000, 0b1000},
+	'G': {0b0110, 0b1001, 0b1000, 0b1011, 0b1001, 0b1001, 0b0111},
+	'H': {0b1001, 0b1001, 0b1001, 0b1111, 0b1001, 0b1001, 0b1001},
+	'I': {0b0111, 0b0010, 0b0010, 0b0010, 0b0010, 0b0010, 0b0111},
+	'J': {0b0001, 0b0001, 0b0001, 0b0001, 0b1001, 0b1001, 0b0110},
+	'K': {0b1001, 0b1001, 0b1010, 0b1100, 0b1010, 0b1001, 0b1001},
+	'L': {0b1000, 0b1000, 0b1000, 0b1000, 0b1000, 0b1000, 0b1111},
+	'M': {0b1001, 0b1111, 0b1111, 0b1001, 0b1001, 0b1001, 0b1001},
+	'N': {0b1001, 0b1101, 0b1101, 0b1011, 0b1011, 0b1001, 0b1001},
+	'O': {0b0110, 0b1001, 0b1001, 0b1001, 0b1001, 0b1001, 0b0110},
+	'P': {0b1110, 0b1001, 0b1001, 0b1110, 0b1000, 0b1000, 0b1000},
+	'Q': {0b0110, 0b1001, 0b1001, 0b1001, 0b1101, 0b1010, 0b0101},
+	'R': {0b1110, 0b1001, 0b1001, 0b1110, 0b1010, 0b1001, 0b1001},
+	'S': {0b0111, 0b1000, 0b1000, 0b0110, 0b0001, 0b0001, 0b1110},
+	'T': {0b1111, 0b0100, 0b0100, 0b0100, 0b0100, 0b0100, 0b0100},
+	'U': {0b1001, 0b1001, 0b1001, 0b1001, 0b1001, 0b1001, 0b0110},
+	'V': {0b1001, 0b1001, 0b1001, 0b1001, 0b1001, 0b0110, 0b0110},
+	'W': {0b1001, 0b1001, 0b1001, 0b1001, 0b1111, 0b1111, 0b1001},
+	'X': {0b1001, 0b1001, 0b0110, 0b0110, 0b0110, 0b1001, 0b1001},
+	'Y': {0b1001, 0b1001, 0b1001, 0b0110, 0b0100, 0b0100, 0b0100},
+	'Z': {0b1111, 0b0001, 0b0010, 0b0100, 0b0100, 0b1000, 0b1111},
+}
+
+// textGlyphs returns the glyphs of the built-in font for drawing the given
+// text.
+func textGlyphs(text string) ([][glyphHeight]byte, error) {
+	text = strings.ToUpper(Transliterate(text))
+	glyphs := [][glyphHeight]byte{}
+	for _, c := range text {
+		g, ok := font[c]
+		if !ok {
+			return nil, fmt.Errorf("character %q can't be drawn on the display", c)
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs, nil
+}
+
+// textWidth returns the width in dots of the given glyphs drawn in a row.
+func textWidth(glyphs [][glyphHeight]byte) int {
+	if len(glyphs) == 0 {
+		return 0
+	}
+	return len(glyphs)*(glyphWidth+1) - 1
+}
+
+// drawGlyphs draws the given glyphs in a row into the image, with the top
+// left corner of the first at the given position.
+func drawGlyphs(img *image.Gray, glyphs [][glyphHeight]byte, left, top int) {
+	for i, g := range glyphs {
+		for y, row := range g {
+			for x := 0; x < glyphWidth; x++ {
+				if row&(1<<uint(glyphWidth-1-x)) != 0 {
+					img.SetGray(left+i*(glyphWidth+1)+x, top+y, color.Gray{0xff})
+				}
+			}
+		}
+	}
+}
+
+// TextImage returns a 16x16 image of the given text drawn in the built-in
+// font, centered, suitable for DisplayImage. Only up to MaxDisplayText
+// characters fit; letters, digits and a few punctuation marks can be used.
+func TextImage(text string) (image.Image, error) {
+	glyphs, err := textGlyphs(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(glyphs) > MaxDisplayText {
+		return nil, fmt.Errorf("text %q is too long for the display: at most %d characters fit", text, MaxDisplayText)
+	}
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+	drawGlyphs(img, glyphs, (16-textWidth(glyphs))/2, (16-glyphHeight)/2)
+	return img, nil
+}
+
+// ScrollImages returns the frames of an animation of the given text, of
+// any length, scrolling from right to left across the display in the
+// built-in font. Each frame is a 16x16 image suitable for DisplayImage and
+// moves the text along by one dot; the text starts just off the right hand
+// edge and finishes just off the left hand edge, so the display is blank
+// at both ends.
+func ScrollImages(text string) ([]image.Image, error) {
+	glyphs, err := textGlyphs(text)
+	if err != nil {
+		return nil, err
+	}
+	width := textWidth(glyphs)
+	strip := image.NewGray(image.Rect(0, 0, width+32, 16))
+	drawGlyphs(strip, glyphs, 16, (16-glyphHeight)/2)
+	frames := []image.Image{}
+	for x := 0; x <= width+16; x++ {
+		frame := image.NewGray(image.Rect(0, 0, 16, 16))
+		for y := 0; y < 16; y++ {
+			copy(frame.Pix[y*frame.Stride:y*frame.Stride+16], strip.Pix[y*strip.Stride+x:])
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// DisplayText returns an SC-55 SysEx command that shows a short piece of
+// text (such as "OK" or a two digit number) on the SC-55 front console's
+// dot-matrix display, drawn large using the built-in font. See TextImage.
+func DisplayText(device DeviceID, text string) ([]byte, error) {
+	img, err := TextImage(text)
+	if err != nil {
+		return nil, err
+	}
+	return DisplayImage(device, img)
+}
+
+// DisplayScroll returns the SysEx commands that scroll the given text
+// across the SC-55 front console's dot-matrix display, one per frame (see
+// ScrollImages). The commands should be sent at a steady rate, which sets
+// the speed of the scrolling.
+func DisplayScroll(device DeviceID, text string) ([][]byte, error) {
+	frames, err := ScrollImages(text)
+	if err != nil {
+		return nil, err
+	}
+	msgs := [][]byte{}
+	for _, img := range frames {
+		msg, err := DisplayImage(device, img)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
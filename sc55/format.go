@@ -0,0 +1,237 @@
+package sc55
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55/notes"
+)
+
+// Formatter converts the values of a register (as passed to Set, with the
+// zero offset applied) to and from text, eg. so that a pan-pot value of
+// -32 can be shown as "L32".
+type Formatter interface {
+	Format(value int) string
+	// Parse parses text in the form returned by Format.
+	Parse(s string) (int, error)
+}
+
+var (
+	// formatters are the formatters that can be named in a "format"
+	// struct tag.
+	formatters = map[string]Formatter{
+		"pan":      panFormatter{},
+		"percent":  percentFormatter{},
+		"pitch":    pitchFormatter{},
+		"tenth-hz": tenthHzFormatter{},
+		"note":     noteFormatter{},
+	}
+	registerFormatters map[*Register]Formatter
+)
+
+// Formatter returns the formatter for the register's values. Registers
+// with value names show them by name; registers with no special format
+// show a plain number, with a sign if the register is signed, followed by
+// the units (if any).
+func (r *Register) Formatter() Formatter {
+	if f, ok := registerFormatters[r]; ok {
+		return f
+	}
+	if names := r.ValueNames(); names != nil {
+		return namesFormatter(names)
+	}
+	return numberFormatter{signed: r.Signed(), units: r.Units()}
+}
+
+// FormatValue formats the given value of the register for display.
+func (r *Register) FormatValue(value int) string {
+	return r.Formatter().Format(value)
+}
+
+// ParseValue parses a value for the register, given either as a number or
+// in the form returned by FormatValue (eg. a value name, "L32" or
+// "442.0 Hz"). A plain number is always taken as the value to pass to Set.
+func (r *Register) ParseValue(s string) (int, error) {
+	if v, err := strconv.Atoi(normalizeSign(s)); err == nil {
+		return v, nil
+	}
+	v, err := r.Formatter().Parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("register %q: %v", r.Name(), err)
+	}
+	return v, nil
+}
+
+// formatterForTag returns the formatter named in a "format" struct tag.
+func formatterForTag(name string) (Formatter, error) {
+	if name == "" {
+		return nil, nil
+	}
+	f, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return f, nil
+}
+
+func addFormatters() {
+	registerFormatters[&MasterTune] = pitchFormatter{}
+	registerFormatters[&MasterVolume] = percentFormatter{}
+	registerFormatters[&MasterPan] = panFormatter{}
+}
+
+// normalizeSign replaces a Unicode minus sign, as used in printed
+// manuals, with a hyphen.
+func normalizeSign(s string) string {
+	return strings.Replace(strings.TrimSpace(s), "−", "-", 1)
+}
+
+// trimUnits removes the given units from the end of s, if present,
+// ignoring case.
+func trimUnits(s, units string) string {
+	s = normalizeSign(s)
+	if len(s) >= len(units) && strings.EqualFold(s[len(s)-len(units):], units) {
+		s = strings.TrimSpace(s[:len(s)-len(units)])
+	}
+	return s
+}
+
+type numberFormatter struct {
+	signed bool
+	units  string
+}
+
+func (f numberFormatter) Format(value int) string {
+	result := strconv.Itoa(value)
+	if f.signed {
+		result = fmt.Sprintf("%+d", value)
+	}
+	if f.units != "" {
+		result += " " + f.units
+	}
+	return result
+}
+
+func (f numberFormatter) Parse(s string) (int, error) {
+	v, err := strconv.Atoi(trimUnits(s, f.units))
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q, want a number", s)
+	}
+	return v, nil
+}
+
+type namesFormatter []string
+
+func (f namesFormatter) Format(value int) string {
+	if value >= 0 && value < len(f) {
+		return f[value]
+	}
+	return strconv.Itoa(value)
+}
+
+func (f namesFormatter) Parse(s string) (int, error) {
+	for i, name := range f {
+		if strings.EqualFold(strings.TrimSpace(s), name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid value %q, want a number or one of: %s", s, strings.Join(f, ", "))
+}
+
+// panFormatter shows pan positions as the front panel does: L63 to R63,
+// with C for the center. On parts and drum notes the lowest value selects
+// a random position for each note, shown as "rnd".
+type panFormatter struct{}
+
+const panRandom = -0x40
+
+func (panFormatter) Format(value int) string {
+	switch {
+	case value == panRandom:
+		return "rnd"
+	case value < 0:
+		return fmt.Sprintf("L%d", -value)
+	case value > 0:
+		return fmt.Sprintf("R%d", value)
+	default:
+		return "C"
+	}
+}
+
+func (panFormatter) Parse(s string) (int, error) {
+	pos := strings.ToUpper(strings.TrimSpace(s))
+	switch {
+	case pos == "C":
+		return 0, nil
+	case pos == "RND" || pos == "RANDOM":
+		return panRandom, nil
+	case pos != "" && (pos[0] == 'L' || pos[0] == 'R'):
+		if v, err := strconv.Atoi(pos[1:]); err == nil && v >= 0 {
+			if pos[0] == 'L' {
+				return -v, nil
+			}
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid pan position %q, want eg. L32, C, R15 or a number", s)
+}
+
+// percentFormatter shows levels from 0 to 127 as a percentage of the
+// maximum.
+type percentFormatter struct{}
+
+func (percentFormatter) Format(value int) string {
+	return fmt.Sprintf("%d%%", int(math.Round(float64(value)*100/0x7f)))
+}
+
+func (percentFormatter) Parse(s string) (int, error) {
+	v, err := strconv.ParseFloat(trimUnits(s, "%"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return int(math.Round(v * 0x7f / 100)), nil
+}
+
+// pitchFormatter shows master-tune values as the frequency of A4.
+type pitchFormatter struct{}
+
+func (pitchFormatter) Format(value int) string {
+	return fmt.Sprintf("%.1f Hz", notes.TuningPitch(value))
+}
+
+func (pitchFormatter) Parse(s string) (int, error) {
+	hz, err := strconv.ParseFloat(trimUnits(s, "hz"), 64)
+	if err != nil || hz <= 0 {
+		return 0, fmt.Errorf("invalid pitch %q, want a frequency in Hz", s)
+	}
+	return notes.MasterTune(hz), nil
+}
+
+// tenthHzFormatter shows values in tenths of a Hz as a signed frequency
+// offset, eg. "+1.5 Hz".
+type tenthHzFormatter struct{}
+
+func (tenthHzFormatter) Format(value int) string {
+	return fmt.Sprintf("%+.1f Hz", float64(value)/10)
+}
+
+func (tenthHzFormatter) Parse(s string) (int, error) {
+	hz, err := strconv.ParseFloat(trimUnits(s, "hz"), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid frequency offset %q, want eg. +1.5 Hz", s)
+	}
+	return int(math.Round(hz * 10)), nil
+}
+
+// noteFormatter shows note numbers by name, with C4 as middle C.
+type noteFormatter struct{}
+
+func (noteFormatter) Format(value int) string {
+	return notes.NumberToName(value)
+}
+
+func (noteFormatter) Parse(s string) (int, error) {
+	return notes.NameToNumber(s)
+}
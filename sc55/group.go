@@ -0,0 +1,88 @@
+package sc55
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Group is the part of the device that a register belongs to. Unlike tags,
+// every register is in exactly one group, which follows from its place in
+// the memory map.
+type Group string
+
+const (
+	// GroupSystem holds settings for the whole device other than the
+	// effects, such as the master volume and master tune.
+	GroupSystem = Group("system")
+	GroupReverb = Group("reverb")
+	GroupChorus = Group("chorus")
+	// GroupDelay, GroupEQ and GroupEFX hold the SC-88 and later effects.
+	GroupDelay = Group("delay")
+	GroupEQ    = Group("eq")
+	GroupEFX   = Group("efx")
+	// GroupPart holds the settings of the individual parts. This includes
+	// voice reserve, which is set per part even though the parts' total is
+	// limited by the whole device.
+	GroupPart = Group("part")
+	// GroupDrum holds the drum setup settings of the drum maps.
+	GroupDrum = Group("drum")
+)
+
+var allGroups = []Group{GroupSystem, GroupReverb, GroupChorus, GroupDelay, GroupEQ, GroupEFX, GroupPart, GroupDrum}
+
+// groupPrefixes maps the prefixes of register names to their groups.
+var groupPrefixes = map[string]Group{
+	"reverb-": GroupReverb,
+	"chorus-": GroupChorus,
+	"delay-":  GroupDelay,
+	"eq-":     GroupEQ,
+	"efx-":    GroupEFX,
+	"part-":   GroupPart,
+	"drum-":   GroupDrum,
+}
+
+// AllGroups returns all the register groups.
+func AllGroups() []Group {
+	return append([]Group{}, allGroups...)
+}
+
+// ParseGroups parses a comma-separated list of group names.
+func ParseGroups(s string) ([]Group, error) {
+	result := []Group{}
+	for _, name := range strings.Split(s, ",") {
+		g := Group(strings.TrimSpace(name))
+		if g == "" {
+			continue
+		}
+		known := false
+		for _, u := range allGroups {
+			known = known || g == u
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown group %q", g)
+		}
+		result = append(result, g)
+	}
+	return result, nil
+}
+
+// Group returns the group that the register belongs to.
+func (r *Register) Group() Group {
+	name := r.Name()
+	for prefix, g := range groupPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return g
+		}
+	}
+	return GroupSystem
+}
+
+// InGroup returns true if the register belongs to any of the given groups.
+func (r *Register) InGroup(groups []Group) bool {
+	for _, g := range groups {
+		if r.Group() == g {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package sc55
+
+import "fmt"
+
+// Identity is the contents of a universal identity reply.
+type Identity struct {
+	// Device is the device ID of the device that replied.
+	Device DeviceID
+	// Family is the device family code; GS sound modules report
+	// ModelIDGS.
+	Family int
+	// Member identifies the model within the family.
+	Member int
+	// Version is the firmware version, in a format that differs between
+	// models.
+	Version [4]byte
+}
+
+// IdentityRequest returns a universal identity request. The device replies
+// with a message that can be decoded with UnmarshalIdentity.
+func IdentityRequest(device DeviceID) []byte {
+	return []byte{SysExStart, 0x7e, byte(device), 0x06, 0x01, SysExEnd}
+}
+
+// UnmarshalIdentity decodes an identity reply from a Roland device.
+func UnmarshalIdentity(msg []byte) (Identity, error) {
+	switch {
+	case Classify(msg) != MessageIdentityReply:
+		return Identity{}, fmt.Errorf("not a Roland identity reply")
+	case len(msg) != 15:
+		return Identity{}, fmt.Errorf("identity reply has wrong length: len=%d", len(msg))
+	}
+	id := Identity{
+		Device: DeviceID(msg[2]),
+		Family: int(msg[6]) | int(msg[7])<<8,
+		Member: int(msg[8]) | int(msg[9])<<8,
+	}
+	copy(id.Version[:], msg[10:14])
+	return id, nil
+}
+
+func (id Identity) String() string {
+	family := fmt.Sprintf("family %04x", id.Family)
+	if id.Family == ModelIDGS {
+		family = "GS sound module"
+	}
+	return fmt.Sprintf("Roland %s, member %04x, version % x", family, id.Member, id.Version[:])
+}
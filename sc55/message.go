@@ -0,0 +1,143 @@
+package sc55
+
+import "fmt"
+
+// Command is the command byte of a Roland SysEx message.
+type Command byte
+
+const (
+	// CommandRQ1 requests the contents of a range of memory.
+	CommandRQ1 = Command(0x11)
+	// CommandDT1 sets the contents of a range of memory.
+	CommandDT1 = Command(0x12)
+)
+
+var commandNames = map[Command]string{
+	CommandRQ1: "RQ1",
+	CommandDT1: "DT1",
+}
+
+func (c Command) String() string {
+	if name, ok := commandNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("command %02x", byte(c))
+}
+
+// Message is a Roland SysEx message being constructed with NewMessage. It is
+// intended for advanced uses such as sending unusual messages or talking
+// to other Roland equipment; most programs should use DataSet, DataGet or
+// the Register methods instead.
+type Message struct {
+	device  DeviceID
+	modelID []byte
+	command Command
+	address int
+	data    []byte
+	size    int
+	width   int
+
+	sizeSet bool
+}
+
+// MessageOption configures a Message; see NewMessage.
+type MessageOption func(*Message)
+
+// NewMessage returns a message to the given device, configured by the given
+// options. By default it is a DT1 to address zero with no data, using the
+// model ID appropriate for the address and three-byte addresses.
+func NewMessage(device DeviceID, opts ...MessageOption) *Message {
+	m := &Message{device: device, command: CommandDT1, width: 3}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// WithAddress sets the address that the message reads or writes.
+func WithAddress(addr int) MessageOption {
+	return func(m *Message) {
+		m.address = addr
+	}
+}
+
+// WithData sets the bytes written by a DT1 message.
+func WithData(data ...byte) MessageOption {
+	return func(m *Message) {
+		m.data = data
+	}
+}
+
+// WithSize makes the message an RQ1 that requests the given number of
+// bytes.
+func WithSize(size int) MessageOption {
+	return func(m *Message) {
+		m.command = CommandRQ1
+		m.size = size
+		m.sizeSet = true
+	}
+}
+
+// WithCommand sets the command byte, for commands other than DT1 and RQ1.
+func WithCommand(c Command) MessageOption {
+	return func(m *Message) {
+		m.command = c
+	}
+}
+
+// WithModelID overrides the model ID, which otherwise depends on the
+// address (see DataSet). Some newer Roland devices have model IDs of more
+// than one byte.
+func WithModelID(id ...byte) MessageOption {
+	return func(m *Message) {
+		m.modelID = id
+	}
+}
+
+// WithAddressWidth sets the number of bytes used for the address and size,
+// for devices that use something other than the usual three.
+func WithAddressWidth(width int) MessageOption {
+	return func(m *Message) {
+		m.width = width
+	}
+}
+
+// Bytes returns the complete SysEx message, including its checksum.
+func (m *Message) Bytes() []byte {
+	return m.AppendBytes(nil)
+}
+
+// MaxDT1Size is the largest number of data bytes that should be sent in a
+// single DT1 message; longer writes are split up by SplitDataSet.
+const MaxDT1Size = 128
+
+// linearAddress converts an address from its wire form, where each byte
+// holds only seven bits, to a plain number that can be used in arithmetic.
+func linearAddress(addr int) int {
+	return (addr>>16&0x7f)<<14 | (addr>>8&0x7f)<<7 | addr&0x7f
+}
+
+// wireAddress is the inverse of linearAddress.
+func wireAddress(lin int) int {
+	return (lin>>14&0x7f)<<16 | (lin>>7&0x7f)<<8 | lin&0x7f
+}
+
+// SplitDataSet is like DataSet, but splits the data into as many DT1
+// messages as necessary so that none has more than MaxDT1Size bytes. Each
+// message is addressed to the part of memory that its data belongs in,
+// with addresses carrying between bytes seven bits at a time as the
+// device expects.
+func SplitDataSet(device DeviceID, addr int, data ...byte) [][]byte {
+	result := [][]byte{}
+	lin := linearAddress(addr)
+	for len(data) > 0 {
+		n := len(data)
+		if n > MaxDT1Size {
+			n = MaxDT1Size
+		}
+		result = append(result, DataSet(device, wireAddress(lin), data[:n]...))
+		lin += n
+		data = data[n:]
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package sc55
+
+// Model identifies a member of the SoundCanvas family. Later models are
+// supersets of earlier ones, and the model determines which registers are
+// available.
+type Model int
+
+const (
+	ModelSC55 Model = iota
+	ModelSC55mk2
+	ModelSC88
+	ModelSC88Pro
+)
+
+var modelNames = map[Model]string{
+	ModelSC55:    "sc55",
+	ModelSC55mk2: "sc55mk2",
+	ModelSC88:    "sc88",
+	ModelSC88Pro: "sc88pro",
+}
+
+// modelMaxVoices is the polyphony of each model.
+var modelMaxVoices = map[Model]int{
+	ModelSC55:    24,
+	ModelSC55mk2: 28,
+	ModelSC88:    64,
+	ModelSC88Pro: 64,
+}
+
+// currentModel is the model selected with SetModel.
+var currentModel = ModelSC55
+
+func (m Model) String() string {
+	return modelNames[m]
+}
+
+// MaxVoices returns the total number of voices that can be reserved across
+// all parts on the model, which is its maximum polyphony.
+func (m Model) MaxVoices() int {
+	return modelMaxVoices[m]
+}
+
+// AllModels returns all the known models, oldest first.
+func AllModels() []Model {
+	return []Model{ModelSC55, ModelSC55mk2, ModelSC88, ModelSC88Pro}
+}
+
+// ModelByName looks up a model by name (eg. "sc88pro"), returning model,
+// true if it exists or ModelSC55, false if there is no such model.
+func ModelByName(name string) (Model, bool) {
+	for m, n := range modelNames {
+		if n == name {
+			return m, true
+		}
+	}
+	return ModelSC55, false
+}
+
+// SetModel selects the model being controlled. Registers that only exist on
+// later models are left out of AllRegisters, RegisterByName and
+// RegisterByAddress unless a model that has them is selected. The default
+// is ModelSC55. To work with more than one model at once, use a Registry
+// for each instead.
+func SetModel(m Model) {
+	currentModel = m
+}
+
+// CurrentModel returns the model selected with SetModel.
+func CurrentModel() Model {
+	return currentModel
+}
+
+// ProbeRegister returns a register that first appeared on the given model,
+// for telling models apart: a module ignores requests for registers that
+// it doesn't have. For ModelSC55, whose registers exist on every model,
+// reading it just checks that the module is there. It returns nil if no
+// register belongs to the model.
+func (m Model) ProbeRegister() *Register {
+	for _, r := range NewRegistry(m).AllRegisters() {
+		if r.Model() == m {
+			return r
+		}
+	}
+	return nil
+}
+
+// Model returns the earliest model that has the register.
+func (r *Register) Model() Model {
+	return registerModel[r]
+}
+
+// Available returns true if the register exists on the current model.
+func (r *Register) Available() bool {
+	return DefaultRegistry().Has(r)
+}
@@ -0,0 +1,108 @@
+// Package notes converts between MIDI note numbers, note names and
+// frequencies, following the conventions used by Roland sound modules.
+package notes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MiddleC is the note number of C4.
+	MiddleC = 60
+	// A4 is the note number of the A above middle C, which the master
+	// tune is set relative to.
+	A4 = 69
+	// ConcertPitch is the frequency of A4 when the master tune is at its
+	// default setting.
+	ConcertPitch = 440.0
+	// Max is the highest MIDI note number.
+	Max = 0x7f
+)
+
+var names = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+
+// pitchClasses maps note letters to their pitch class.
+var pitchClasses = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+// NameToNumber parses a note name such as C4, F#2 or Bb-1 into a MIDI note
+// number, using the Roland convention that C4 is middle C (note 60), so
+// that the full range is C-1 to G9. A plain note number is also accepted.
+func NameToNumber(name string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 0 || n > Max {
+			return 0, fmt.Errorf("note number %d out of range, want 0 <= x <= %d", n, Max)
+		}
+		return n, nil
+	}
+	if name == "" {
+		return 0, fmt.Errorf("empty note name")
+	}
+	pc, ok := pitchClasses[strings.ToUpper(name[:1])[0]]
+	if !ok {
+		return 0, fmt.Errorf("invalid note name %q", name)
+	}
+	rest := name[1:]
+	switch {
+	case strings.HasPrefix(rest, "#"):
+		pc, rest = pc+1, rest[1:]
+	case strings.HasPrefix(rest, "b"):
+		pc, rest = pc-1, rest[1:]
+	}
+	octave, err := strconv.Atoi(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid note name %q: missing octave number", name)
+	}
+	n := (octave+1)*12 + pc
+	if n < 0 || n > Max {
+		return 0, fmt.Errorf("note %q out of range, want C-1 to G9", name)
+	}
+	return n, nil
+}
+
+// NumberToName returns the name of a MIDI note number, eg. C4 for 60.
+// Sharps are used rather than flats.
+func NumberToName(n int) string {
+	octave := n / 12
+	if n < 0 {
+		octave = (n - 11) / 12
+	}
+	return fmt.Sprintf("%s%d", names[n-octave*12], octave-1)
+}
+
+// TuningPitch returns the frequency of A4 for the given value of the
+// master-tune register, which is in tenths of a cent from -100.0 to +100.0
+// cents around concert pitch.
+func TuningPitch(masterTune int) float64 {
+	return ConcertPitch * math.Pow(2, float64(masterTune)/12000)
+}
+
+// Frequency returns the frequency in Hz of the given note at concert
+// pitch.
+func Frequency(note int) float64 {
+	return TunedFrequency(note, 0)
+}
+
+// TunedFrequency returns the frequency in Hz of the given note when the
+// master-tune register has the given value.
+func TunedFrequency(note, masterTune int) float64 {
+	return TuningPitch(masterTune) * math.Pow(2, float64(note-A4)/12)
+}
+
+// FromFrequency returns the note closest to the given frequency at the
+// given master tune setting, and how far the frequency is from it in
+// cents.
+func FromFrequency(hz float64, masterTune int) (int, float64) {
+	semitones := 12 * math.Log2(hz/TuningPitch(masterTune))
+	note := int(math.Round(semitones)) + A4
+	return note, (semitones - float64(note-A4)) * 100
+}
+
+// MasterTune returns the value of the master-tune register that tunes A4
+// to the given frequency. It is the inverse of TuningPitch; the result may
+// be outside the range of the register.
+func MasterTune(pitch float64) int {
+	return int(math.Round(12000 * math.Log2(pitch/ConcertPitch)))
+}
@@ -0,0 +1,133 @@
+package sc55
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// maxParserSysEx is the longest SysEx message that a Parser will collect;
+// anything longer is assumed to be garbage and is discarded.
+const maxParserSysEx = 64 * 1024
+
+// Event is a SoundCanvas message decoded by a Parser.
+type Event struct {
+	Type MessageType
+	// Device is the device ID the message was sent to or from.
+	Device DeviceID
+	// Address is the address read or written by a DT1 or RQ1.
+	Address int
+	// Data is the data written by a DT1, the body of an identity reply, or
+	// the volume (LSB first) of a master volume message.
+	Data []byte
+	// Size is the number of bytes requested by an RQ1.
+	Size int
+	// Raw is the complete SysEx message.
+	Raw []byte
+}
+
+// Parser reads SoundCanvas messages from a stream of MIDI bytes, such as
+// the raw output of a MIDI port or a file captured from one. Unlike
+// UnmarshalSet, the input doesn't have to be split into messages first:
+// channel messages, realtime bytes and SysEx messages for other devices
+// are skipped, and messages may arrive in any number of fragments.
+type Parser struct {
+	r       *bufio.Reader
+	buf     []byte
+	inSysEx bool
+}
+
+// NewParser returns a Parser that reads from the given stream.
+func NewParser(r io.Reader) *Parser {
+	return &Parser{r: bufio.NewReader(r)}
+}
+
+// Next returns the next SoundCanvas message in the stream, or io.EOF at
+// the end of the stream. If a message is recognized but can't be decoded
+// (eg. because of a bad checksum), an error is returned; Next can then be
+// called again to continue with the following messages.
+func (p *Parser) Next() (*Event, error) {
+	for {
+		msg, err := p.nextSysEx()
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeEvent(msg)
+		if err != nil || e != nil {
+			return e, err
+		}
+	}
+}
+
+// SplitSysEx splits a stream of MIDI bytes, such as the contents of a .syx
+// file, into complete SysEx messages. Like a Parser, it skips anything in
+// between messages, realtime bytes inside them, and messages that were cut
+// short, but it returns every SysEx message rather than only SoundCanvas
+// ones.
+func SplitSysEx(data []byte) [][]byte {
+	p := NewParser(bytes.NewReader(data))
+	result := [][]byte{}
+	for {
+		msg, err := p.nextSysEx()
+		if err != nil {
+			return result
+		}
+		result = append(result, msg)
+	}
+}
+
+// nextSysEx returns the next complete SysEx message in the stream.
+func (p *Parser) nextSysEx() ([]byte, error) {
+	for {
+		b, err := p.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b >= 0xf8:
+			// Realtime messages can appear anywhere, even in the
+			// middle of a SysEx message.
+		case b == SysExStart:
+			p.buf, p.inSysEx = []byte{b}, true
+		case b == SysExEnd && p.inSysEx:
+			p.inSysEx = false
+			return append(p.buf, b), nil
+		case b >= 0x80:
+			// Any other status byte ends a SysEx message early.
+			p.inSysEx = false
+		case p.inSysEx:
+			p.buf = append(p.buf, b)
+			if len(p.buf) > maxParserSysEx {
+				p.inSysEx = false
+			}
+		}
+	}
+}
+
+// decodeEvent decodes a complete SysEx message, returning nil if it is not
+// a SoundCanvas message.
+func decodeEvent(msg []byte) (*Event, error) {
+	e := &Event{Type: Classify(msg), Raw: msg}
+	var err error
+	switch e.Type {
+	case MessageUnknown:
+		return nil, nil
+	case MessageGMReset, MessageGM2On, MessageIdentityRequest:
+		e.Device = DeviceID(msg[2])
+	case MessageMasterVolume:
+		e.Device = DeviceID(msg[2])
+		e.Data = msg[5:7]
+	case MessageIdentityReply:
+		e.Device = DeviceID(msg[2])
+		e.Data = msg[5 : len(msg)-1]
+	case MessageRQ1:
+		e.Device, e.Address, e.Size, err = UnmarshalGet(msg)
+	default:
+		e.Device, e.Address, e.Data, err = UnmarshalSet(msg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %v message: %v", e.Type, err)
+	}
+	return e, nil
+}
@@ -0,0 +1,111 @@
+package sc55
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func FuzzUnmarshalSet(f *testing.F) {
+	f.Add(DataSet(0x10, MasterVolume.Address, 0x64))
+	f.Add(DataSet(0x10, MasterTune.Address, 0x00, 0x04, 0x00, 0x00))
+	f.Add(DisplayMessage(0x10, "hello"))
+	f.Add(DataGet(0x10, MasterVolume.Address, 1))
+	f.Add([]byte{SysExStart, SysExEnd})
+	f.Fuzz(func(t *testing.T, msg []byte) {
+		dev, addr, data, err := UnmarshalSet(msg)
+		if err != nil {
+			return
+		}
+		// Anything accepted must be exactly what DataSet would have
+		// sent, apart from the model ID, which depends on the
+		// address.
+		want := DataSet(dev, addr, data...)
+		want[3] = msg[3]
+		if !bytes.Equal(msg, want) {
+			t.Errorf("UnmarshalSet(% x) = %02x, %06x, % x; DataSet gives % x", msg, dev, addr, data, want)
+		}
+	})
+}
+
+func FuzzParser(f *testing.F) {
+	f.Add(DataSet(0x10, MasterVolume.Address, 0x64))
+	f.Add(append(DataSet(0x10, MasterPan.Address, 0x40), ResetGS(0x10)...))
+	f.Add([]byte{0x90, 0x3c, 0x40, SysExStart, 0x41, 0xf8, 0x10, SysExEnd})
+	f.Add(ResetGM(0x7f))
+	f.Fuzz(func(t *testing.T, stream []byte) {
+		p := NewParser(bytes.NewReader(stream))
+		// Every call must consume at least one byte, so this many
+		// calls are always enough to reach the end.
+		for i := 0; i <= len(stream); i++ {
+			e, err := p.Next()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err == nil && e == nil {
+				t.Fatalf("Next() returned no event and no error")
+			}
+		}
+		t.Fatalf("Next() did not reach the end of a %d byte stream", len(stream))
+	})
+}
+
+func TestUnmarshalSetInvalid(t *testing.T) {
+	valid := DataSet(0x10, MasterVolume.Address, 0x64)
+	badChecksum := append([]byte{}, valid...)
+	badChecksum[len(badChecksum)-2] ^= 0x01
+	badData := append([]byte{}, valid...)
+	badData[8]++
+	tests := []struct {
+		name string
+		msg  []byte
+	}{
+		{"empty", nil},
+		{"no end", valid[:len(valid)-1]},
+		{"truncated", append(append([]byte{}, valid[:6]...), SysExEnd)},
+		{"truncated before checksum", append(append([]byte{}, valid[:len(valid)-2]...), SysExEnd)},
+		{"cut short by another message", append(append([]byte{}, valid[:7]...), ResetGS(0x10)...)},
+		{"bad checksum", badChecksum},
+		{"data changed", badData},
+		{"RQ1", DataGet(0x10, MasterVolume.Address, 1)},
+	}
+	for _, tt := range tests {
+		if dev, addr, data, err := UnmarshalSet(tt.msg); err == nil {
+			t.Errorf("%s: UnmarshalSet(% x) = %02x, %06x, % x, want error", tt.name, tt.msg, dev, addr, data)
+		}
+	}
+	if _, _, _, err := UnmarshalSet(valid); err != nil {
+		t.Errorf("UnmarshalSet(% x) failed: %v", valid, err)
+	}
+}
+
+func TestParserInvalid(t *testing.T) {
+	valid := DataSet(0x10, MasterVolume.Address, 0x64)
+	badChecksum := append([]byte{}, valid...)
+	badChecksum[len(badChecksum)-2] ^= 0x01
+	var stream []byte
+	// A message cut short by a note on is skipped entirely.
+	stream = append(stream, valid[:7]...)
+	stream = append(stream, 0x90, 0x3c, 0x40)
+	// A bad checksum is reported, but doesn't stop the parser.
+	stream = append(stream, badChecksum...)
+	stream = append(stream, valid...)
+	// The stream ends in the middle of a message.
+	stream = append(stream, valid[:len(valid)-1]...)
+
+	p := NewParser(bytes.NewReader(stream))
+	if e, err := p.Next(); err == nil {
+		t.Fatalf("Next() = %+v, want bad checksum error", e)
+	}
+	e, err := p.Next()
+	if err != nil {
+		t.Fatalf("Next() failed: %v", err)
+	}
+	if e.Type != MessageDT1 || e.Address != MasterVolume.Address || !bytes.Equal(e.Data, []byte{0x64}) {
+		t.Errorf("Next() = %+v, want DT1 of 64 to master volume", e)
+	}
+	if e, err := p.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Next() = %+v, %v, want EOF", e, err)
+	}
+}
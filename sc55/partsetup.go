@@ -0,0 +1,81 @@
+package sc55
+
+import "fmt"
+
+// PartSetup holds the settings most often changed together when setting
+// up a part, for Part.Setup. Fields that are nil are left as they are.
+type PartSetup struct {
+	Tone *Tone
+	// Channel is the MIDI channel (1-16) that the part receives on, or 0
+	// for none.
+	Channel *int
+	// DrumMap makes the part a rhythm part playing the given drum map (1
+	// or 2), or a normal part if it is 0.
+	DrumMap *int
+	// Level, Pan, Reverb and Chorus are values for the part-level,
+	// pan-pot, reverb-send-level and chorus-send-level registers, as for
+	// Set.
+	Level, Pan, Reverb, Chorus *int
+}
+
+// toneNumber returns the value of the tone-number-cc register that selects
+// the given tone: the bank select value in the high byte and the program
+// change value in the low byte.
+func toneNumber(t Tone) (int, error) {
+	switch {
+	case t.Bank < 0 || t.Bank > 0x7f:
+		return 0, fmt.Errorf("invalid bank %d, want 0 <= x <= 127", t.Bank)
+	case t.Program < 1 || t.Program > 128:
+		return 0, fmt.Errorf("invalid program %d, want 1 <= x <= 128", t.Program)
+	}
+	return t.Bank<<8 | (t.Program - 1), nil
+}
+
+// Setup returns the SysEx commands that apply the given settings to the
+// part, using as few messages as possible (see SetMany). Unlike Set, an
+// error is returned if any value is out of range rather than clamping it.
+func (p *Part) Setup(device DeviceID, s PartSetup) ([][]byte, error) {
+	values := map[*Register]int{}
+	if s.Tone != nil {
+		n, err := toneNumber(*s.Tone)
+		if err != nil {
+			return nil, err
+		}
+		values[&p.ToneNumber] = n
+	}
+	if s.Channel != nil {
+		switch {
+		case *s.Channel == 0:
+			values[&p.RxChannel] = p.RxChannel.Max
+		case *s.Channel < 1 || *s.Channel > 16:
+			return nil, fmt.Errorf("invalid channel %d, want 1 <= x <= 16 or 0 for off", *s.Channel)
+		default:
+			values[&p.RxChannel] = *s.Channel - 1
+		}
+	}
+	if s.DrumMap != nil {
+		if *s.DrumMap < 0 || *s.DrumMap > NumDrumMaps {
+			return nil, fmt.Errorf("invalid drum map %d, want 0 <= x <= %d", *s.DrumMap, NumDrumMaps)
+		}
+		values[&p.UseForRhythm] = *s.DrumMap
+	}
+	levels := []struct {
+		r *Register
+		v *int
+	}{
+		{&p.PartLevel, s.Level},
+		{&p.PanPot, s.Pan},
+		{&p.ReverbSendLevel, s.Reverb},
+		{&p.ChorusSendLevel, s.Chorus},
+	}
+	for _, l := range levels {
+		if l.v == nil {
+			continue
+		}
+		if err := l.r.CheckRange(*l.v); err != nil {
+			return nil, err
+		}
+		values[l.r] = *l.v
+	}
+	return SetMany(device, values)
+}
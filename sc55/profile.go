@@ -0,0 +1,114 @@
+package sc55
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile describes a particular product in the SoundCanvas family. Some
+// products, such as the CM-300 and SCC-1, share the sound engine and
+// registers of another model but lack its front panel, so the profile
+// records the model whose registers it has along with its quirks.
+type Profile struct {
+	// Name is the name used to select the profile with -model.
+	Name string
+	// Description is the product's full name.
+	Description string
+	// Model is the model whose registers the product has.
+	Model Model
+	// Display is true if the product has a front panel display that
+	// can show messages and images.
+	Display bool
+}
+
+var profiles = []Profile{
+	{"sc55", "Roland SC-55", ModelSC55, true},
+	{"sc55mk2", "Roland SC-55mkII", ModelSC55mk2, true},
+	{"sc88", "Roland SC-88", ModelSC88, true},
+	{"sc88pro", "Roland SC-88Pro", ModelSC88Pro, true},
+	{"cm300", "Roland CM-300 (an SC-55 without a front panel)", ModelSC55, false},
+	{"cm500", "Roland CM-500 (a CM-300 with an LA synth)", ModelSC55, false},
+	{"scc1", "Roland SCC-1 (an SC-55 on an ISA card)", ModelSC55, false},
+}
+
+// currentProfile is the profile selected with SetProfile.
+var currentProfile = profiles[0]
+
+// AllProfiles returns all the known profiles: one for each model, followed
+// by the products based on them.
+func AllProfiles() []Profile {
+	return append([]Profile{}, profiles...)
+}
+
+// ProfileByName looks up a profile by name (eg. "cm300"), returning
+// profile, true if it exists or the SC-55 profile, false if there is no
+// such profile.
+func ProfileByName(name string) (Profile, bool) {
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return profiles[0], false
+}
+
+// SetProfile selects the product being controlled, and with it the model
+// (see SetModel).
+func SetProfile(p Profile) {
+	currentProfile = p
+	SetModel(p.Model)
+}
+
+// CurrentProfile returns the profile selected with SetProfile.
+func CurrentProfile() Profile {
+	return currentProfile
+}
+
+// DeviceIDRegisterName is the name of the register that changes a
+// product's device ID. None of the built-in register maps have one, since
+// the products they describe take their device ID from the front panel (or
+// a DIP switch), but firmware that allows it can be described with a
+// custom register of this name.
+const DeviceIDRegisterName = "device-id"
+
+// DeviceIDRegister returns the register that changes the device ID of the
+// profile's product, or an error if there is no way to change it by SysEx.
+func (p Profile) DeviceIDRegister() (*Register, error) {
+	r, ok := NewRegistry(p.Model).RegisterByName(DeviceIDRegisterName)
+	if !ok {
+		return nil, fmt.Errorf("the %s has no SysEx message to change its device ID; it is set from the front panel", p.Description)
+	}
+	return r, nil
+}
+
+// ButtonRegisterPrefix starts the names of registers that simulate front
+// panel button presses ("remote switches"), eg. "button-mute". As with
+// DeviceIDRegisterName, the built-in register maps have none, since the
+// messages aren't documented for any of the built-in products, but they can
+// be added as custom registers for firmware that responds to them.
+const ButtonRegisterPrefix = "button-"
+
+// Buttons returns the names of the front panel buttons that can be pressed
+// by SysEx on the profile's product, without ButtonRegisterPrefix.
+func (p Profile) Buttons() []string {
+	result := []string{}
+	for _, r := range NewRegistry(p.Model).AllRegisters() {
+		if name, ok := strings.CutPrefix(r.Name(), ButtonRegisterPrefix); ok {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ButtonRegister returns the register that presses the named front panel
+// button on the profile's product, or an error if it can't be pressed by
+// SysEx.
+func (p Profile) ButtonRegister(name string) (*Register, error) {
+	r, ok := NewRegistry(p.Model).RegisterByName(ButtonRegisterPrefix + name)
+	if !ok {
+		return nil, fmt.Errorf("the %s has no SysEx message to press the %q button", p.Description, name)
+	}
+	return r, nil
+}
@@ -0,0 +1,115 @@
+package sc55
+
+import "sort"
+
+// Registry is the set of registers available on one model. The package
+// level functions such as AllRegisters and RegisterByName use a Registry
+// for the model chosen with SetModel; a program that deals with several
+// models at once (eg. converting settings from an SC-88 to an SC-55) can
+// create a Registry for each instead.
+//
+// The registers themselves are shared: a register that exists on both
+// models is the same *Register in both registries. A Registry holds the
+// registers that existed when it was created, so it doesn't include any
+// added later with AddRegister.
+type Registry struct {
+	model     Model
+	byName    map[string]*Register
+	byAddress map[int]*Register
+	// registers holds every register on the model, sorted by address.
+	registers []*Register
+}
+
+// NewRegistry returns the registry of registers available on the given
+// model.
+func NewRegistry(m Model) *Registry {
+	reg := &Registry{
+		model:     m,
+		byName:    make(map[string]*Register),
+		byAddress: make(map[int]*Register),
+	}
+	for name, r := range registersByName {
+		if r.Model() > m {
+			continue
+		}
+		reg.byName[name] = r
+		reg.byAddress[r.Address] = r
+		reg.registers = append(reg.registers, r)
+	}
+	sort.Slice(reg.registers, func(i, j int) bool {
+		return reg.registers[i].Address < reg.registers[j].Address
+	})
+	return reg
+}
+
+// defaultRegistry is the registry returned by DefaultRegistry. It is
+// created when first needed, and again after the model is changed or a
+// register is added.
+var defaultRegistry *Registry
+
+// DefaultRegistry returns the registry for the model chosen with SetModel.
+func DefaultRegistry() *Registry {
+	if defaultRegistry == nil || defaultRegistry.model != currentModel {
+		defaultRegistry = NewRegistry(currentModel)
+	}
+	return defaultRegistry
+}
+
+// Model returns the model that the registry is for.
+func (reg *Registry) Model() Model {
+	return reg.model
+}
+
+// Has returns true if the given register exists on the registry's model.
+func (reg *Registry) Has(r *Register) bool {
+	return reg.byAddress[r.Address] == r
+}
+
+// RegisterByName looks up a register by name, returning register, true if it
+// exists on the registry's model or nil, false if there is no such register.
+func (reg *Registry) RegisterByName(name string) (*Register, bool) {
+	r, ok := reg.byName[name]
+	return r, ok
+}
+
+// RegisterByAddress looks up a register by address, returning register, true
+// if it exists on the registry's model or nil, false if there is no such
+// register.
+func (reg *Registry) RegisterByAddress(addr int) (*Register, bool) {
+	r, ok := reg.byAddress[addr]
+	return r, ok
+}
+
+// AllRegisters returns a slice containing all registers on the registry's
+// model, sorted by address.
+func (reg *Registry) AllRegisters() []*Register {
+	return append([]*Register{}, reg.registers...)
+}
+
+// PartCount returns the number of parts on the registry's model.
+func (reg *Registry) PartCount() int {
+	if reg.model >= ModelSC88 {
+		return NumParts
+	}
+	return 16
+}
+
+// PartByNumber returns the given part, looked up by number as for the
+// package level PartByNumber, or nil if the registry's model doesn't have
+// that part.
+func (reg *Registry) PartByNumber(i int) *Part {
+	if i < 1 || i > reg.PartCount() {
+		return nil
+	}
+	return &parts[i-1]
+}
+
+// Catalog returns descriptions of all the registers on the registry's
+// model, in address order.
+func (reg *Registry) Catalog() []RegisterInfo {
+	result := []RegisterInfo{}
+	for _, r := range reg.AllRegisters() {
+		result = append(result, r.Info())
+	}
+	return result
+}
@@ -0,0 +1,108 @@
+package sc55
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// Fit is how FitImage makes an image of another size fill the 16x16
+// display.
+type Fit string
+
+const (
+	// FitStretch scales the whole image to 16x16, distorting it if it
+	// isn't square.
+	FitStretch = Fit("stretch")
+	// FitCrop scales the image to cover the display and cuts off the
+	// edges that don't fit, keeping the center.
+	FitCrop = Fit("crop")
+	// FitLetterbox scales the whole image to fit within the display and
+	// leaves the rest blank.
+	FitLetterbox = Fit("letterbox")
+)
+
+// Scaling is the method that FitImage uses to scale images.
+type Scaling string
+
+const (
+	// ScaleNearest uses the nearest pixel, which keeps pixel art sharp.
+	ScaleNearest = Scaling("nearest")
+	// ScaleBilinear blends neighbouring pixels, which suits photos and
+	// other detailed images better.
+	ScaleBilinear = Scaling("bilinear")
+)
+
+// rect is a rectangle with fractional coordinates.
+type rect struct{ x, y, w, h float64 }
+
+// fitRects returns the area of the source image to use, of the given
+// size, and the area of the display to draw it into.
+func fitRects(fit Fit, w, h float64) (rect, rect, error) {
+	src, dest := rect{0, 0, w, h}, rect{0, 0, 16, 16}
+	switch fit {
+	case FitStretch:
+	case FitCrop:
+		scale := math.Max(16/w, 16/h)
+		src.w, src.h = 16/scale, 16/scale
+		src.x, src.y = (w-src.w)/2, (h-src.h)/2
+	case FitLetterbox:
+		scale := math.Min(16/w, 16/h)
+		dest.w, dest.h = math.Round(w*scale), math.Round(h*scale)
+		dest.x, dest.y = math.Floor((16-dest.w)/2), math.Floor((16-dest.h)/2)
+	default:
+		return src, dest, fmt.Errorf("unknown fit %q: want %s, %s or %s", fit, FitStretch, FitCrop, FitLetterbox)
+	}
+	return src, dest, nil
+}
+
+// grayAt returns the brightness of the pixel of the image at the given
+// coordinates relative to its top left corner, clamped to its bounds.
+func grayAt(img image.Image, x, y int) float64 {
+	b := img.Bounds()
+	x = Clamp(x, 0, b.Dx()-1)
+	y = Clamp(y, 0, b.Dy()-1)
+	return float64(color.Gray16Model.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray16).Y)
+}
+
+// FitImage scales an image of any size to the 16x16 needed by
+// DisplayImage, in the given way. Images that are already 16x16 are
+// returned unchanged.
+func FitImage(img image.Image, fit Fit, scaling Scaling) (image.Image, error) {
+	b := img.Bounds()
+	if b == image.Rect(0, 0, 16, 16) {
+		return img, nil
+	}
+	if b.Empty() {
+		return nil, fmt.Errorf("image is empty")
+	}
+	if scaling != ScaleNearest && scaling != ScaleBilinear {
+		return nil, fmt.Errorf("unknown scaling %q: want %s or %s", scaling, ScaleNearest, ScaleBilinear)
+	}
+	src, dest, err := fitRects(fit, float64(b.Dx()), float64(b.Dy()))
+	if err != nil {
+		return nil, err
+	}
+	result := image.NewGray16(image.Rect(0, 0, 16, 16))
+	for y := int(dest.y); y < int(dest.y+dest.h); y++ {
+		for x := int(dest.x); x < int(dest.x+dest.w); x++ {
+			// The center of the display pixel, mapped onto the source.
+			sx := src.x + (float64(x)-dest.x+0.5)*src.w/dest.w
+			sy := src.y + (float64(y)-dest.y+0.5)*src.h/dest.h
+			var v float64
+			if scaling == ScaleNearest {
+				v = grayAt(img, int(sx), int(sy))
+			} else {
+				x0, y0 := math.Floor(sx-0.5), math.Floor(sy-0.5)
+				fx, fy := sx-0.5-x0, sy-0.5-y0
+				ix, iy := int(x0), int(y0)
+				top := grayAt(img, ix, iy)*(1-fx) + grayAt(img, ix+1, iy)*fx
+				bottom := grayAt(img, ix, iy+1)*(1-fx) + grayAt(img, ix+1, iy+1)*fx
+				v = top*(1-fy) + bottom*fy
+			}
+			result.SetGray16(x, y, color.Gray16{uint16(math.Round(v))})
+		}
+	}
+	return result, nil
+}
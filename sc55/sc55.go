@@ -6,12 +6,21 @@ import (
 	"image"
 	"reflect"
 	"sort"
+	"strconv"
 )
 
 // DeviceID represents the address of an SC-55 so that multiple can be
 // present on the same MIDI bus. Usually "DefaultDevice" should be used.
 type DeviceID byte
 
+// Matches returns true if a message with device ID other concerns device d,
+// ie. the IDs are the same or either is BroadcastDevice. This allows replies
+// to a broadcast query, which carry the replying device's own ID, to be
+// matched up with the query.
+func (d DeviceID) Matches(other DeviceID) bool {
+	return d == other || d == BroadcastDevice || other == BroadcastDevice
+}
+
 // Register represents a SoundCanvas memory register.
 type Register struct {
 	Address, Size int
@@ -22,83 +31,113 @@ type Register struct {
 // Part represents the set of registers associated with a part.
 type Part struct {
 	ToneNumber          Register `name:"tone-number-cc"`
-	RxChannel           Register `name:"rx-channel"`
-	RxPitchBend         Register `name:"rx-pitch-bend"`
-	RxChPressure        Register `name:"rx-ch-pressure"`
-	RxProgramChange     Register `name:"rx-program-change"`
-	RxControlChange     Register `name:"rx-control-change"`
-	RxPolyPressure      Register `name:"rx-poly-pressure"`
-	RxNoteMessage       Register `name:"rx-note-message"`
-	RxRPN               Register `name:"rx-rpn"`
-	RxNRPN              Register `name:"rx-nrpn"`
-	RxModulation        Register `name:"rx-modulation"`
-	RxVolume            Register `name:"rx-volume"`
-	RxPanPot            Register `name:"rx-pan-pot"`
-	RxExpression        Register `name:"rx-expression"`
-	RxHold1             Register `name:"rx-hold-1"`
-	RxPortamento        Register `name:"rx-portamento"`
-	RxSostenuto         Register `name:"rx-sostenuto"`
-	RxSoft              Register `name:"rx-soft"`
-	MonoPolyMode        Register `name:"mono-poly-mode"`
-	AssignMode          Register `name:"assign-mode"`
-	UseForRhythm        Register `name:"use-for-rhythm"`
-	PitchKeyShift       Register `name:"pitch-key-shift" important:"true"`
-	PitchOffsetFine     Register `name:"pitch-offset-fine"`
-	PartLevel           Register `name:"part-level" important:"true"`
-	VelocitySenseDepth  Register `name:"velocity-sense-depth"`
-	VelocitySenseOffset Register `name:"velocity-sense-offset"`
-	PanPot              Register `name:"pan-pot" important:"true"`
-	KeyRangeLow         Register `name:"key-range-low"`
-	KeyRangeHigh        Register `name:"key-range-high"`
-	CC1Controller       Register `name:"cc-1-controller"`
-	CC2Controller       Register `name:"cc-2-controller"`
-	ChorusSendLevel     Register `name:"chorus-send-level" important:"true"`
-	ReverbSendLevel     Register `name:"reverb-send-level" important:"true"`
-	RxBankSelect        Register `name:"rx-bank-select"`
-	ToneModify1         Register `name:"tone-modify-1"`
-	ToneModify2         Register `name:"tone-modify-2"`
-	ToneModify3         Register `name:"tone-modify-3"`
-	ToneModify4         Register `name:"tone-modify-4"`
-	ToneModify5         Register `name:"tone-modify-5"`
-	ToneModify6         Register `name:"tone-modify-6"`
-	ToneModify7         Register `name:"tone-modify-7"`
-	ToneModify8         Register `name:"tone-modify-8"`
-	/* These are all one register:
-	ScaleTuningC        Register `name:"scale-tuning-c"`
-	ScaleTuningCSharp   Register `name:"scale-tuning-cs"`
-	ScaleTuningD        Register `name:"scale-tuning-d"`
-	ScaleTuningDSharp   Register `name:"scale-tuning-ds"`
-	ScaleTuningE        Register `name:"scale-tuning-e"`
-	ScaleTuningF        Register `name:"scale-tuning-f"`
-	ScaleTuningFSharp   Register `name:"scale-tuning-fs"`
-	ScaleTuningG        Register `name:"scale-tuning-g"`
-	ScaleTuningGSharp   Register `name:"scale-tuning-gs"`
-	ScaleTuningA        Register `name:"scale-tuning-a"`
-	ScaleTuningASharp   Register `name:"scale-tuning-as"`
-	ScaleTuningB        Register `name:"scale-tuning-b"`
-	*/
+	RxChannel           Register `name:"rx-channel" tags:"routing"`
+	RxPitchBend         Register `name:"rx-pitch-bend" tags:"routing,rarely-used" values:"switch"`
+	RxChPressure        Register `name:"rx-ch-pressure" tags:"routing,rarely-used" values:"switch"`
+	RxProgramChange     Register `name:"rx-program-change" tags:"routing,rarely-used" values:"switch"`
+	RxControlChange     Register `name:"rx-control-change" tags:"routing,rarely-used" values:"switch"`
+	RxPolyPressure      Register `name:"rx-poly-pressure" tags:"routing,rarely-used" values:"switch"`
+	RxNoteMessage       Register `name:"rx-note-message" tags:"routing,rarely-used" values:"switch"`
+	RxRPN               Register `name:"rx-rpn" tags:"routing,rarely-used" values:"switch"`
+	RxNRPN              Register `name:"rx-nrpn" tags:"routing,rarely-used" values:"switch"`
+	RxModulation        Register `name:"rx-modulation" tags:"routing,rarely-used" values:"switch"`
+	RxVolume            Register `name:"rx-volume" tags:"routing,rarely-used" values:"switch"`
+	RxPanPot            Register `name:"rx-pan-pot" tags:"routing,rarely-used" values:"switch"`
+	RxExpression        Register `name:"rx-expression" tags:"routing,rarely-used" values:"switch"`
+	RxHold1             Register `name:"rx-hold-1" tags:"routing,rarely-used" values:"switch"`
+	RxPortamento        Register `name:"rx-portamento" tags:"routing,rarely-used" values:"switch"`
+	RxSostenuto         Register `name:"rx-sostenuto" tags:"routing,rarely-used" values:"switch"`
+	RxSoft              Register `name:"rx-soft" tags:"routing,rarely-used" values:"switch"`
+	MonoPolyMode        Register `name:"mono-poly-mode" tags:"rarely-used" values:"mono-poly"`
+	AssignMode          Register `name:"assign-mode" tags:"rarely-used" values:"assign-mode"`
+	UseForRhythm        Register `name:"use-for-rhythm" tags:"routing" values:"rhythm"`
+	PitchKeyShift       Register `name:"pitch-key-shift" tags:"front-panel,tuning" units:"semitones"`
+	PitchOffsetFine     Register `name:"pitch-offset-fine" tags:"tuning" encoding:"nibbles" units:"0.1 Hz" format:"tenth-hz"`
+	PartLevel           Register `name:"part-level" tags:"front-panel" cc:"7"`
+	VelocitySenseDepth  Register `name:"velocity-sense-depth" tags:"rarely-used"`
+	VelocitySenseOffset Register `name:"velocity-sense-offset" tags:"rarely-used"`
+	PanPot              Register `name:"pan-pot" tags:"front-panel" cc:"10" format:"pan"`
+	KeyRangeLow         Register `name:"key-range-low" tags:"routing" units:"note number" format:"note"`
+	KeyRangeHigh        Register `name:"key-range-high" tags:"routing" units:"note number" format:"note"`
+	CC1Controller       Register `name:"cc-1-controller" tags:"routing,rarely-used"`
+	CC2Controller       Register `name:"cc-2-controller" tags:"routing,rarely-used"`
+	ChorusSendLevel     Register `name:"chorus-send-level" tags:"front-panel,effects" cc:"93"`
+	ReverbSendLevel     Register `name:"reverb-send-level" tags:"front-panel,effects" cc:"91"`
+	RxBankSelect        Register `name:"rx-bank-select" tags:"routing" values:"switch"`
+	ToneModify1         Register `name:"tone-modify-1" tags:"rarely-used"`
+	ToneModify2         Register `name:"tone-modify-2" tags:"rarely-used"`
+	ToneModify3         Register `name:"tone-modify-3" tags:"rarely-used"`
+	ToneModify4         Register `name:"tone-modify-4" tags:"rarely-used"`
+	ToneModify5         Register `name:"tone-modify-5" tags:"rarely-used"`
+	ToneModify6         Register `name:"tone-modify-6" tags:"rarely-used"`
+	ToneModify7         Register `name:"tone-modify-7" tags:"rarely-used"`
+	ToneModify8         Register `name:"tone-modify-8" tags:"rarely-used"`
+	// Scale tuning is written as a single 12-byte block; see
+	// SetScaleTuning.
+}
+
+// DrumNote represents the drum setup registers for a single note of one of
+// the two drum maps, which allow individual drum sounds to be adjusted.
+type DrumNote struct {
+	PitchCoarse     Register `name:"pitch-coarse" tags:"tuning" units:"semitones"`
+	Level           Register `name:"level"`
+	AssignGroup     Register `name:"assign-group" tags:"rarely-used"`
+	PanPot          Register `name:"pan-pot" format:"pan"`
+	ReverbSendLevel Register `name:"reverb-send-level" tags:"effects"`
+	ChorusSendLevel Register `name:"chorus-send-level" tags:"effects"`
+	RxNoteOff       Register `name:"rx-note-off" tags:"routing,rarely-used" values:"switch"`
+	RxNoteOn        Register `name:"rx-note-on" tags:"routing" values:"switch"`
 }
 
 const (
 	// DefaultDevice is the default device ID unless otherwise configured.
 	DefaultDevice = DeviceID(0x10)
 
-	manufacturerID = 0x41
-
-	sysExStart = 0xf0
-	sysExEnd   = 0xf7
+	// BroadcastDevice is the device ID that every device responds to,
+	// whatever its own device ID is set to.
+	BroadcastDevice = DeviceID(0x7f)
+
+	// ManufacturerID is Roland's manufacturer ID, the second byte of every
+	// message.
+	ManufacturerID = 0x41
+
+	// ModelIDGS is the model ID used for most messages: GS sound modules
+	// all respond to it.
+	ModelIDGS = 0x42
+	// ModelIDDisplay is the model ID used for messages to the front panel
+	// display, which is addressed as a separate device.
+	ModelIDDisplay = 0x45
+
+	// ModelIDGM is the fourth byte of the GM reset message, in the
+	// position where a model ID would usually go.
+	ModelIDGM = 0x09
+
+	// SysExStart and SysExEnd are the status bytes that begin and end
+	// every SysEx message.
+	SysExStart = 0xf0
+	SysExEnd   = 0xf7
 )
 
 const (
-	cmdRQ1 = 0x11
-	cmdDT1 = 0x12
+	AddrDisplayMessage = 0x100000
+	AddrDisplayImage   = 0x100100
+
+	AddrSystemModeSet = 0x00007F
+	AddrModeSet       = 0x40007F
+
+	// portBOffset is added to the address of a port A register to get
+	// the same register for port B.
+	portBOffset = 0x100000
 )
 
 const (
-	AddrDisplayMessage = 0x100000
-	AddrDisplayImage   = 0x100100
+	// NumDrumMaps is the number of drum maps that can be edited.
+	NumDrumMaps = 2
 
-	AddrModeSet = 0x40007F
+	// DrumNoteMin and DrumNoteMax are the range of notes that have drum
+	// setup registers.
+	DrumNoteMin = 27
+	DrumNoteMax = 88
 )
 
 var (
@@ -122,23 +161,72 @@ var (
 	ChorusDepth         = Register{0x40013e, 1, 0x00, 0x7f, 0}
 	ChorusToReverbLevel = Register{0x40013f, 1, 0x00, 0x7f, 0}
 
-	parts              [16]Part
+	parts              [NumParts]Part
+	voiceReserve       [NumParts]Register
+	drumNotes          [NumDrumMaps][DrumNoteMax - DrumNoteMin + 1]DrumNote
 	registersByAddress map[int]*Register
 	registersByName    map[string]*Register
 	registerName       map[*Register]string
-	isImportant        map[*Register]bool
+	registerTags       map[*Register][]Tag
+	registerModel      map[*Register]Model
+	registerEncoding   map[*Register]Encoding
 )
 
-func addRegister(name string, r *Register, important bool) {
+// AddRegister adds a register that isn't in the built-in tables, such as an
+// undocumented or device-specific address, so that it can be found with
+// RegisterByName and AllRegisters like any other. The register exists on
+// the given model and later ones. An error is returned if the name is
+// already taken, or if the register's memory overlaps that of another
+// register.
+func AddRegister(name string, r *Register, m Model, e Encoding, tags ...Tag) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("register has no name")
+	case r.Size < 1 || r.Size > 4:
+		return fmt.Errorf("register %q: invalid size %d, want 1 <= x <= 4", name, r.Size)
+	case r.Address < 0 || r.Address > 0x7f7f7f:
+		return fmt.Errorf("register %q: invalid address %x", name, r.Address)
+	case r.Min < 0 || r.Min > r.Max:
+		return fmt.Errorf("register %q: invalid range %d to %d", name, r.Min, r.Max)
+	}
+	if _, ok := registersByName[name]; ok {
+		return fmt.Errorf("register %q already exists", name)
+	}
+	if _, ok := textRegistersByName[name]; ok {
+		return fmt.Errorf("text register %q already exists", name)
+	}
+	for _, other := range registersByAddress {
+		if r.Address < other.Address+other.Size && other.Address < r.Address+r.Size {
+			return fmt.Errorf("register %q at %06x overlaps register %q at %06x", name, r.Address, other.Name(), other.Address)
+		}
+	}
+	for _, t := range AllTextRegisters() {
+		if r.Address < t.Address+t.Size && t.Address < r.Address+r.Size {
+			return fmt.Errorf("register %q at %06x overlaps text register %q at %06x", name, r.Address, t.Name(), t.Address)
+		}
+	}
+	registerEncoding[r] = e
+	if _, err := r.encode(r.Max); err != nil {
+		delete(registerEncoding, r)
+		return fmt.Errorf("register %q: maximum can't be encoded: %v", name, err)
+	}
+	addRegister(name, r, tags...)
+	registerModel[r] = m
+	return nil
+}
+
+func addRegister(name string, r *Register, tags ...Tag) {
 	registersByName[name] = r
 	registersByAddress[r.Address] = r
 	registerName[r] = name
-	if important {
-		isImportant[r] = true
-	}
+	registerTags[r] = tags
+	defaultRegistry = nil
 }
 
-func checksum(data []byte) byte {
+// Checksum returns the Roland checksum of the given bytes, which are the
+// address and data of a DT1 or RQ1 message. It is the value that makes the
+// low 7 bits of the sum of all the bytes zero.
+func Checksum(data []byte) byte {
 	sum := 0
 	for _, b := range data {
 		sum += int(b)
@@ -146,20 +234,22 @@ func checksum(data []byte) byte {
 	return byte(128-(sum%128)) % 128
 }
 
-func modelID(addr int) byte {
-	if addr < MasterTune.Address {
-		return 0x45
+func modelIDForAddress(addr int) byte {
+	// The display is addressed as a separate device.
+	if addr&0xff0000 == AddrDisplayMessage&0xff0000 {
+		return ModelIDDisplay
 	}
-	return 0x42
+	return ModelIDGS
 }
 
 func marshalInt24(val int) []byte {
-	return []byte{
-		// Address:
-		byte((val >> 16) & 0xff),
-		byte((val >> 8) & 0xff),
-		byte(val & 0xff),
-	}
+	return marshalInt(val, 3)
+}
+
+// marshalInt encodes an address or size in the given number of bytes, most
+// significant first.
+func marshalInt(val, width int) []byte {
+	return appendInt(make([]byte, 0, width), val, width)
 }
 
 func unmarshalInt24(data []byte) int {
@@ -169,44 +259,39 @@ func unmarshalInt24(data []byte) int {
 // DataSet returns an SC-55 DT1 command that sets the value of a range
 // of memory in the SC-55.
 func DataSet(device DeviceID, addr int, data ...byte) []byte {
-	// A different model ID is used for different address ranges:
-	body := marshalInt24(addr)
-	body = append(body, data...)
-	msg := []byte{sysExStart, manufacturerID, byte(device), modelID(addr), cmdDT1}
-	msg = append(msg, body...)
-	msg = append(msg, checksum(body))
-	msg = append(msg, sysExEnd)
-	return msg
+	return AppendDataSet(nil, device, addr, data...)
 }
 
 // DataGet returns an SC-55 RQ1 command that requests the contents of a range
 // of memory in the SC-55.
 func DataGet(device DeviceID, addr, size int) []byte {
-	body := marshalInt24(addr)
-	body = append(body, marshalInt24(size)...)
-	msg := []byte{sysExStart, manufacturerID, byte(device), modelID(addr), cmdRQ1}
-	msg = append(msg, body...)
-	msg = append(msg, checksum(body))
-	msg = append(msg, sysExEnd)
-	return msg
+	return AppendDataGet(nil, device, addr, size)
 }
 
-// UnmarshalSet decodes a DT1 command, returning the device ID of the device that
-// sent it, the address, and value.
-func UnmarshalSet(msg []byte) (DeviceID, int, []byte, error) {
+// unmarshalMessage checks the framing and checksum of a Roland SysEx message
+// with the given command, and returns the device ID, the address, and the
+// bytes that follow the address.
+func unmarshalMessage(msg []byte, cmd Command) (DeviceID, int, []byte, error) {
 	switch {
-	case msg[0] != sysExStart || msg[len(msg)-1] != sysExEnd:
+	case len(msg) < 2 || msg[0] != SysExStart || msg[len(msg)-1] != SysExEnd:
 		return 0, 0, nil, fmt.Errorf("failed to unmarshal: not a SysEx command")
-	case msg[1] != manufacturerID:
-		return 0, 0, nil, fmt.Errorf("wrong manufacturer: want %02x, got %02x", manufacturerID, msg[1])
-	case msg[3] != 0x42 && msg[3] != 0x45:
-		return 0, 0, nil, fmt.Errorf("wrong device: want 0x42 or 0x45, got %02x", msg[3])
-	case msg[4] != cmdDT1:
-		return 0, 0, nil, fmt.Errorf("wrong command type, want %02x, got %02x", cmdDT1, msg[4])
 	case len(msg) < 10:
-		return 0, 0, nil, fmt.Errorf("DT1 command too short: len=%d", len(msg))
+		return 0, 0, nil, fmt.Errorf("%s command too short: len=%d", cmd, len(msg))
+	case msg[1] != ManufacturerID:
+		return 0, 0, nil, fmt.Errorf("wrong manufacturer: want %02x, got %02x", ManufacturerID, msg[1])
+	case msg[3] != ModelIDGS && msg[3] != ModelIDDisplay:
+		return 0, 0, nil, fmt.Errorf("wrong device: want %02x or %02x, got %02x", ModelIDGS, ModelIDDisplay, msg[3])
+	case msg[4] != byte(cmd):
+		return 0, 0, nil, fmt.Errorf("wrong command type, want %02x, got %02x", byte(cmd), msg[4])
 	}
-	wantChecksum := checksum(msg[5 : len(msg)-2])
+	// A status byte in the middle means that the message was cut short
+	// and run together with whatever followed it.
+	for i, b := range msg[1 : len(msg)-1] {
+		if b >= 0x80 {
+			return 0, 0, nil, fmt.Errorf("unexpected status byte %02x at offset %d", b, i+1)
+		}
+	}
+	wantChecksum := Checksum(msg[5 : len(msg)-2])
 	gotChecksum := msg[len(msg)-2]
 	if wantChecksum != gotChecksum {
 		return 0, 0, nil, fmt.Errorf("wrong checksum: calculated=%02x, got=%02x", wantChecksum, gotChecksum)
@@ -214,16 +299,37 @@ func UnmarshalSet(msg []byte) (DeviceID, int, []byte, error) {
 	return DeviceID(msg[2]), unmarshalInt24(msg[5:8]), msg[8 : len(msg)-2], nil
 }
 
+// UnmarshalSet decodes a DT1 command, returning the device ID of the device that
+// sent it, the address, and value.
+func UnmarshalSet(msg []byte) (DeviceID, int, []byte, error) {
+	return unmarshalMessage(msg, CommandDT1)
+}
+
+// UnmarshalGet decodes an RQ1 command, returning the device ID of the device
+// it was sent to, the address, and the number of bytes requested.
+func UnmarshalGet(msg []byte) (DeviceID, int, int, error) {
+	dev, addr, body, err := unmarshalMessage(msg, CommandRQ1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if len(body) != 3 {
+		return 0, 0, 0, fmt.Errorf("RQ1 command has wrong length: len=%d", len(msg))
+	}
+	return dev, addr, unmarshalInt24(body), nil
+}
+
 // DisplayMessage returns an SC-55 SysEx command that displays a message on the
-// SC-55 front console.
+// SC-55 front console. The message is transliterated first, so any UTF-8
+// string can be given.
 func DisplayMessage(device DeviceID, msg string) []byte {
 	// The data sheet says the maximum is 32, but I found that a message of
 	// length 32 causes some weird screen corruption like a buffer is being
 	// overflowed.
-	if len(msg) > 31 {
-		msg = msg[:31]
+	data := EncodeDisplay(msg)
+	if len(data) > 31 {
+		data = data[:31]
 	}
-	return DataSet(device, AddrDisplayMessage, []byte(msg)...)
+	return DataSet(device, AddrDisplayMessage, data...)
 }
 
 // DisplayImage returns an SC-55 SysEx command that displays an image on the
@@ -247,23 +353,85 @@ func DisplayImage(device DeviceID, img image.Image) ([]byte, error) {
 }
 
 // ResetGM returns an SC-55 SysEx command that sets the SC-55 into GM mode.
+// All parameters are returned to their General MIDI defaults, and
+// GS-specific settings (such as variation tones) are not available until a
+// GS reset is received.
 func ResetGM(device DeviceID) []byte {
 	return []byte{
-		sysExStart,
-		manufacturerID,
+		SysExStart,
+		ManufacturerID,
 		byte(device),
-		0x09, // General MIDI message
-		0x01, // General MIDI on
-		sysExEnd,
+		ModelIDGM, // General MIDI message
+		0x01,      // General MIDI on
+		SysExEnd,
 	}
 }
 
 // ResetGS returns an SC-55 SysEx command that sets the SC-55 into GS mode.
+// All GS parameters (system, effects and parts) are returned to their
+// defaults, but the module-wide system mode is left unchanged.
 func ResetGS(device DeviceID) []byte {
 	return DataSet(device, AddrModeSet, 0)
 }
 
-func clamp(x, min, max int) int {
+// ResetGM2 returns the universal GM2 System On message. Modules that don't
+// support General MIDI 2 (including the SC-55 and SC-88) ignore it, so it
+// is usually sent along with ResetGM or ResetGS in setup tracks meant for
+// many modules.
+func ResetGM2(device DeviceID) []byte {
+	return []byte{SysExStart, 0x7e, byte(device), ModelIDGM, 0x03, SysExEnd}
+}
+
+// UniversalMasterVolume returns the universal realtime Master Volume
+// message, which sets the overall volume of any GM module (unlike the
+// MasterVolume register, which is specific to GS). The volume is a 14-bit
+// value from 0 to 16383.
+func UniversalMasterVolume(device DeviceID, volume int) ([]byte, error) {
+	if volume < 0 || volume > 0x3fff {
+		return nil, fmt.Errorf("invalid master volume %d, want 0 <= x <= %d", volume, 0x3fff)
+	}
+	return []byte{
+		SysExStart, 0x7f, byte(device),
+		0x04, 0x01, // Device control, master volume
+		byte(volume & 0x7f), byte(volume >> 7),
+		SysExEnd,
+	}, nil
+}
+
+// SystemMode is the module mode chosen with a System Mode Set message on
+// models that have one (SC-88 and later).
+type SystemMode byte
+
+const (
+	// SystemModeDouble is "MODE-1", double module mode, where the device
+	// acts as two 16-part modules on ports A and B.
+	SystemModeDouble = SystemMode(0x00)
+
+	// SystemModeSingle is "MODE-2", single module mode, where port B is
+	// unused and the device behaves like a single 16-part module.
+	SystemModeSingle = SystemMode(0x01)
+)
+
+// ModeSet returns a "system mode set" SysEx command that selects the given
+// module mode. Changing mode reinitializes everything, like a GS reset but
+// also including the system parameters. The original SC-55 has no system
+// mode and may not respond to this message, so scripts that must work on
+// every model should follow it with ResetGS.
+func ModeSet(device DeviceID, mode SystemMode) []byte {
+	return DataSet(device, AddrSystemModeSet, byte(mode))
+}
+
+// ResetAll returns a "system mode set" SysEx command, which reinitializes
+// everything including the system mode itself; on modules with a double
+// module mode (SC-88 and later) this selects double module mode, the
+// power-on default. Unlike ResetGS, it therefore does not preserve any
+// settings.
+func ResetAll(device DeviceID) []byte {
+	return ModeSet(device, SystemModeDouble)
+}
+
+// Clamp returns x limited to the range min..max.
+func Clamp(x, min, max int) int {
 	switch {
 	case x < min:
 		return min
@@ -277,7 +445,19 @@ func clamp(x, min, max int) int {
 // Important returns true if the given register is "important", ie. one of the
 // settings that is shown on the physical front panel of the device.
 func (r *Register) Important() bool {
-	return isImportant[r]
+	return r.HasTag(TagFrontPanel)
+}
+
+// Signed returns true if the register's values are relative to a zero
+// offset, and so can be negative (eg. key shift, pan, tuning).
+func (r *Register) Signed() bool {
+	return r.Zero != 0
+}
+
+// Range returns the minimum and maximum values that can be passed to Set
+// or returned by Unmarshal, ie. with the zero offset already applied.
+func (r *Register) Range() (int, int) {
+	return r.Min - r.Zero, r.Max - r.Zero
 }
 
 // Get returns an SC-55 SysEx command to get the value of the given register.
@@ -285,16 +465,143 @@ func (r *Register) Get(device DeviceID) []byte {
 	return DataGet(device, r.Address, r.Size)
 }
 
-// Set returns an SC-55 SysEx command to set the given register to the given value.
-func (r *Register) Set(device DeviceID, value int) []byte {
-	value = clamp(value+r.Zero, r.Min, r.Max)
-	bytes := []byte{
-		byte(value & 0xff),
-		byte((value >> 8) & 0xff),
-		byte((value >> 16) & 0xff),
-		byte((value >> 24) & 0xff),
+// Encoding describes how a register's value is laid out in its bytes on
+// the wire. Either way, values are sent most significant part first, as in
+// Roland's documentation.
+type Encoding int
+
+const (
+	// EncodingBytes registers use 8 bits of each byte, though since each
+	// must be a valid MIDI data byte the top bit is always zero.
+	EncodingBytes Encoding = iota
+
+	// EncodingNibbles registers are "nibblized": only the low 4 bits of
+	// each byte are used (eg. master tune, pitch offset fine).
+	EncodingNibbles
+)
+
+// Encoding returns the way the register's value is encoded.
+func (r *Register) Encoding() Encoding {
+	return registerEncoding[r]
+}
+
+// bitsPerByte returns the number of bits of the value held in each byte.
+func (e Encoding) bitsPerByte() uint {
+	if e == EncodingNibbles {
+		return 4
 	}
-	return DataSet(device, r.Address, bytes[:r.Size]...)
+	return 8
+}
+
+// encode converts a register value (with zero offset already applied) into
+// the bytes sent on the wire. Multi-byte values are sent most significant
+// byte first, and every byte must be a valid 7-bit MIDI data byte.
+func (r *Register) encode(value int) ([]byte, error) {
+	return r.appendEncoded(nil, value)
+}
+
+// appendEncoded is like encode, but appends the bytes to dst. On error, dst
+// is returned unchanged.
+func (r *Register) appendEncoded(dst []byte, value int) ([]byte, error) {
+	if r.Size < 1 || r.Size > 4 {
+		return dst, fmt.Errorf("unsupported register size %d", r.Size)
+	}
+	bits := int(r.Encoding().bitsPerByte())
+	mask := 1<<bits - 1
+	if value>>(bits*r.Size) != 0 {
+		return dst, fmt.Errorf("value %#x does not fit in %d bytes", value, r.Size)
+	}
+	orig := len(dst)
+	for i := r.Size - 1; i >= 0; i-- {
+		b := byte(value >> (bits * i) & mask)
+		if b > 0x7f {
+			return dst[:orig], fmt.Errorf("value %#x cannot be represented: byte %02x is not a valid MIDI data byte", value, b)
+		}
+		dst = append(dst, b)
+	}
+	return dst, nil
+}
+
+// decode is the inverse of encode.
+func (r *Register) decode(payload []byte) (int, error) {
+	if len(payload) != r.Size {
+		return 0, fmt.Errorf("wrong size: want %d bytes, got %d", r.Size, len(payload))
+	}
+	bits := r.Encoding().bitsPerByte()
+	result := 0
+	for _, b := range payload {
+		if int(b) >= 1<<bits {
+			return 0, fmt.Errorf("byte %02x is out of range for a %d-bit encoded register", b, bits)
+		}
+		result = (result << bits) | int(b)
+	}
+	return result, nil
+}
+
+// Set returns an SC-55 SysEx command to set the given register to the given
+// value. The value is clamped to the register's range; an error is returned
+// if it can't be encoded in the register's size.
+func (r *Register) Set(device DeviceID, value int) ([]byte, error) {
+	return r.AppendSet(nil, device, value)
+}
+
+// SetStrict is like Set, but returns an error instead of clamping if the
+// value is out of the register's range.
+func (r *Register) SetStrict(device DeviceID, value int) ([]byte, error) {
+	if err := r.CheckRange(value); err != nil {
+		return nil, err
+	}
+	return r.Set(device, value)
+}
+
+// CheckRange returns an error if the given value is outside the range that
+// can be passed to Set without being clamped.
+func (r *Register) CheckRange(value int) error {
+	if min, max := r.Range(); value < min || value > max {
+		return fmt.Errorf("register %q: value %d out of range, want %d <= x <= %d", r.Name(), value, min, max)
+	}
+	return nil
+}
+
+// SetRaw returns an SC-55 SysEx command that writes the given bytes to the
+// register's address exactly as given. Unlike Set, no zero offset is
+// applied, the value is not clamped to the register's range, and the data
+// need not match the register size. This is useful for experimenting with
+// undocumented values or reproducing captured messages byte for byte.
+func (r *Register) SetRaw(device DeviceID, data ...byte) []byte {
+	return DataSet(device, r.Address, data...)
+}
+
+// SetMany returns SysEx commands that set the given registers to the given
+// values, like Set. Registers at contiguous addresses are written together
+// in a single DT1 of up to MaxDT1Size bytes, so that setting up many
+// registers (eg. all the parameters of a part) takes far fewer messages.
+func SetMany(device DeviceID, values map[*Register]int) ([][]byte, error) {
+	regs := []*Register{}
+	for r := range values {
+		regs = append(regs, r)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].Address < regs[j].Address })
+	result := [][]byte{}
+	addr, data := 0, []byte{}
+	for _, r := range regs {
+		encoded, err := r.encode(Clamp(values[r]+r.Zero, r.Min, r.Max))
+		if err != nil {
+			return nil, fmt.Errorf("register %q: %v", r.Name(), err)
+		}
+		if len(data) > 0 && (r.Address != addr+len(data) || len(data)+len(encoded) > MaxDT1Size) {
+			result = append(result, DataSet(device, addr, data...))
+			data = nil
+		}
+		if len(data) == 0 {
+			addr = r.Address
+		}
+		data = append(data, encoded...)
+	}
+	if len(data) > 0 {
+		result = append(result, DataSet(device, addr, data...))
+	}
+	return result, nil
 }
 
 // Unmarshal decodes an SC-55 SysEx DT1 command (typically received from the SC-55
@@ -307,17 +614,25 @@ func (r *Register) Unmarshal(msg []byte) (DeviceID, int, error) {
 		return 0, 0, err
 	case addr != r.Address:
 		return 0, 0, fmt.Errorf("wrong register: want address %x, got %x", r.Address, addr)
-	case len(payload) != r.Size:
-		return 0, 0, fmt.Errorf("wrong size: want %d bytes, got %d", r.Size, len(payload))
 	}
-	result := 0
-	for i, b := range payload {
-		result |= int(b) << uint(i*8)
+	value, err := r.Decode(payload)
+	if err != nil {
+		return 0, 0, err
+	}
+	return dev, value, nil
+}
+
+// Decode returns the value of the register given the raw bytes of its
+// contents, as found in the payload of a DT1 command.
+func (r *Register) Decode(payload []byte) (int, error) {
+	result, err := r.decode(payload)
+	if err != nil {
+		return 0, err
 	}
 	if result < r.Min || result > r.Max {
-		return 0, 0, fmt.Errorf("register value out of range, want %d <= x <= %d, got x=%d", r.Min, r.Max, result)
+		return 0, fmt.Errorf("register value out of range, want %d <= x <= %d, got x=%d", r.Min, r.Max, result)
 	}
-	return dev, result - r.Zero, nil
+	return result - r.Zero, nil
 }
 
 // Name returns the name of the given register.
@@ -326,32 +641,22 @@ func (r *Register) Name() string {
 }
 
 // RegisterByName looks up a register by name, returning register, true if it
-// exists or nil, false if there is no such register.
+// exists on the current model or nil, false if there is no such register.
 func RegisterByName(name string) (*Register, bool) {
-	r, ok := registersByName[name]
-	return r, ok
+	return DefaultRegistry().RegisterByName(name)
 }
 
 // RegisterByAddress looks up a register by address, returning register, true
-// if it exists or nil, false if there is no such register.
+// if it exists on the current model or nil, false if there is no such
+// register.
 func RegisterByAddress(addr int) (*Register, bool) {
-	r, ok := registersByAddress[addr]
-	return r, ok
+	return DefaultRegistry().RegisterByAddress(addr)
 }
 
-// AllRegisters returns a slice containing all known SC-55 registers, sorted
-// by address.
+// AllRegisters returns a slice containing all registers on the current
+// model, sorted by address.
 func AllRegisters() []*Register {
-	addrs := []int{}
-	for a := range registersByAddress {
-		addrs = append(addrs, a)
-	}
-	sort.IntSlice(addrs).Sort()
-	result := []*Register{}
-	for _, a := range addrs {
-		result = append(result, registersByAddress[a])
-	}
-	return result
+	return DefaultRegistry().AllRegisters()
 }
 
 var templatePart = Part{
@@ -377,7 +682,7 @@ var templatePart = Part{
 	AssignMode:          Register{0x14, 1, 0x00, 0x02, 0},
 	UseForRhythm:        Register{0x15, 1, 0x00, 0x02, 0},
 	PitchKeyShift:       Register{0x16, 1, 0x28, 0x58, 0x40},
-	PitchOffsetFine:     Register{0x17, 2, 0x08, 0xf8, 0x800},
+	PitchOffsetFine:     Register{0x17, 2, 0x08, 0xf8, 0x80},
 	PartLevel:           Register{0x19, 1, 0x00, 0x7f, 0},
 	VelocitySenseDepth:  Register{0x1a, 1, 0x00, 0x7f, 0},
 	VelocitySenseOffset: Register{0x1b, 1, 0x00, 0x7f, 0},
@@ -397,69 +702,211 @@ var templatePart = Part{
 	ToneModify6:         Register{0x35, 1, 0x0e, 0x72, 0x40},
 	ToneModify7:         Register{0x36, 1, 0x0e, 0x72, 0x40},
 	ToneModify8:         Register{0x37, 1, 0x0e, 0x72, 0x40},
-	/*
-		ScaleTuningC:        Register{0x40, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningCSharp:   Register{0x41, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningD:        Register{0x42, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningDSharp:   Register{0x43, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningE:        Register{0x44, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningF:        Register{0x45, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningFSharp:   Register{0x46, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningG:        Register{0x47, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningGSharp:   Register{0x48, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningA:        Register{0x49, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningASharp:   Register{0x4a, 1, 0x00, 0x7f, 0x40},
-		ScaleTuningB:        Register{0x4b, 1, 0x00, 0x7f, 0x40},
-	*/
-}
-
-func (p *Part) init(prefix string, addr int) {
-	*p = templatePart
+}
+
+// addRegisters adds all the registers in the given struct (eg. a *Part),
+// offset by the given address, that are available on the given model and
+// later. The "tags" tag lists the register's tags, separated by commas.
+// Fields with a "model" tag can require a later model, an "encoding" tag
+// of "nibbles" marks nibblized registers, a "values" tag names the table
+// of value names to use (see ValueNames), a "units" tag gives the units
+// of the values, a "cc" tag gives the equivalent MIDI controller, and a
+// "format" tag names how the values are shown (see Formatter).
+func addRegisters(p interface{}, prefix string, addr int, m Model) {
 	v := reflect.ValueOf(p).Elem()
 	for i := 0; i < v.NumField(); i++ {
 		tag := v.Type().Field(i).Tag
 		name := tag.Get("name")
-		_, important := tag.Lookup("important")
+		tags, err := ParseTags(tag.Get("tags"))
+		if err != nil {
+			panic(fmt.Sprintf("register %q: %v", prefix+name, err))
+		}
 		r := v.Field(i).Addr().Interface().(*Register)
 		r.Address += addr
-		addRegister(prefix+name, r, important)
+		addRegister(prefix+name, r, tags...)
+		registerModel[r] = m
+		if tag.Get("encoding") == "nibbles" {
+			registerEncoding[r] = EncodingNibbles
+		}
+		names, err := valueNamesForTag(tag.Get("values"))
+		if err != nil {
+			panic(fmt.Sprintf("register %q: %v", prefix+name, err))
+		}
+		if names != nil {
+			registerValueNames[r] = names
+		}
+		if units := tag.Get("units"); units != "" {
+			registerUnits[r] = units
+		}
+		format, err := formatterForTag(tag.Get("format"))
+		if err != nil {
+			panic(fmt.Sprintf("register %q: %v", prefix+name, err))
+		}
+		if format != nil {
+			registerFormatters[r] = format
+		}
+		if cc, ok := tag.Lookup("cc"); ok {
+			n, err := strconv.Atoi(cc)
+			if err != nil || n < 0 || n > 0x7f {
+				panic(fmt.Sprintf("register %q: invalid controller %q", prefix+name, cc))
+			}
+			registerControllers[r] = n
+		}
+		if modelName, ok := tag.Lookup("model"); ok {
+			tagModel, ok := ModelByName(modelName)
+			if !ok {
+				panic(fmt.Sprintf("register %q: unknown model %q", prefix+name, modelName))
+			}
+			if tagModel > m {
+				registerModel[r] = tagModel
+			}
+		}
 	}
 }
 
-// PartByNumber returns the given part, looked up by number in the
-// range 1-16. This corresponds to the number shown on the front panel.
+func (p *Part) init(prefix string, addr int, m Model) {
+	*p = templatePart
+	addRegisters(p, prefix, addr, m)
+}
+
+const scaleTuningOffset = 0x40
+
+// SetScaleTuning returns an SC-55 SysEx command that sets the scale tuning
+// of the part in a single message. Each value is the offset in cents
+// (-64 to +63) of one note of the octave, starting from C; values outside
+// the range are clamped.
+func (p *Part) SetScaleTuning(device DeviceID, cents [12]int) []byte {
+	data := make([]byte, len(cents))
+	for i, c := range cents {
+		data[i] = byte(Clamp(c+0x40, 0x00, 0x7f))
+	}
+	return DataSet(device, p.ToneNumber.Address+scaleTuningOffset, data...)
+}
+
+// Drum setup registers are laid out with one 256-byte page for each
+// parameter, indexed by note number.
+var templateDrumNote = DrumNote{
+	PitchCoarse:     Register{0x100, 1, 0x00, 0x7f, 0x40},
+	Level:           Register{0x200, 1, 0x00, 0x7f, 0},
+	AssignGroup:     Register{0x300, 1, 0x00, 0x7f, 0},
+	PanPot:          Register{0x400, 1, 0x00, 0x7f, 0x40},
+	ReverbSendLevel: Register{0x500, 1, 0x00, 0x7f, 0},
+	ChorusSendLevel: Register{0x600, 1, 0x00, 0x7f, 0},
+	RxNoteOff:       Register{0x700, 1, 0x00, 0x01, 0},
+	RxNoteOn:        Register{0x800, 1, 0x00, 0x01, 0},
+}
+
+func (d *DrumNote) init(prefix string, addr int) {
+	*d = templateDrumNote
+	addRegisters(d, prefix, addr, ModelSC55)
+}
+
+// NumParts is the number of parts on the largest models. The SC-55 has 16;
+// 32-part models (SC-88 and later) have a second block of 16 parts
+// addressed as port B.
+const NumParts = 32
+
+// PartCount returns the number of parts on the current model.
+func PartCount() int {
+	return DefaultRegistry().PartCount()
+}
+
+// PartByNumber returns the given part, looked up by number in the range
+// 1-16, or 1-32 on 32-part models where parts 17-32 are port B parts 1-16.
+// This corresponds to the number shown on the front panel.
 func PartByNumber(i int) *Part {
-	if i < 1 || i > 16 {
+	return DefaultRegistry().PartByNumber(i)
+}
+
+// SetDrumMap returns an SC-55 SysEx command that makes the part a rhythm
+// part playing the given drum map (1 or 2), or a normal part if drumMap is
+// zero.
+func (p *Part) SetDrumMap(device DeviceID, drumMap int) ([]byte, error) {
+	if drumMap < 0 || drumMap > NumDrumMaps {
+		return nil, fmt.Errorf("invalid drum map %d, want 0 <= x <= %d", drumMap, NumDrumMaps)
+	}
+	return p.UseForRhythm.Set(device, drumMap)
+}
+
+// partIndex returns the index of the part within the device's memory map
+// (see init), plus 16 for port B parts.
+func (p *Part) partIndex() int {
+	index := (p.ToneNumber.Address >> 8) & 0x0f
+	if p.ToneNumber.Address >= 0x400000+portBOffset {
+		index += 16
+	}
+	return index
+}
+
+// VoiceReserve returns the register holding the number of voices reserved
+// for the part. Unlike the other part registers it lives in the system
+// block, so it isn't a field of Part.
+func (p *Part) VoiceReserve() *Register {
+	return &voiceReserve[p.partIndex()]
+}
+
+// SetVoiceReserve returns an SC-55 SysEx command that sets the number of
+// voices reserved for every part in a single message. voices is indexed by
+// part number minus one; an error is returned if any value is out of range
+// or the total is more than the current model's MaxVoices.
+func SetVoiceReserve(device DeviceID, voices [16]int) ([]byte, error) {
+	data := make([]byte, len(voices))
+	total := 0
+	for i, v := range voices {
+		r := parts[i].VoiceReserve()
+		if v < r.Min || v > r.Max {
+			return nil, fmt.Errorf("part %d: invalid voice reserve %d, want %d <= x <= %d", i+1, v, r.Min, r.Max)
+		}
+		data[r.Address-voiceReserve[0].Address] = byte(v)
+		total += v
+	}
+	if max := CurrentModel().MaxVoices(); total > max {
+		return nil, fmt.Errorf("total voice reserve %d is more than the %s maximum of %d", total, CurrentModel(), max)
+	}
+	return DataSet(device, voiceReserve[0].Address, data...), nil
+}
+
+// DrumNoteByNumber returns the drum setup registers for the given note of
+// the given drum map (1 or 2), or nil if there are none.
+func DrumNoteByNumber(drumMap, note int) *DrumNote {
+	if drumMap < 1 || drumMap > NumDrumMaps || note < DrumNoteMin || note > DrumNoteMax {
 		return nil
 	}
-	return &parts[i-1]
+	return &drumNotes[drumMap-1][note-DrumNoteMin]
 }
 
 func init() {
 	registersByAddress = make(map[int]*Register)
 	registersByName = make(map[string]*Register)
 	registerName = make(map[*Register]string)
-	isImportant = make(map[*Register]bool)
-
-	addRegister("master-tune", &MasterTune, true)
-	addRegister("master-volume", &MasterVolume, true)
-	addRegister("master-key-shift", &MasterKeyShift, true)
-	addRegister("master-pan", &MasterPan, true)
-	addRegister("reverb-macro", &ReverbMacro, false)
-	addRegister("reverb-character", &ReverbCharacter, false)
-	addRegister("reverb-pre-lpf", &ReverbPreLPF, false)
-	addRegister("reverb-level", &ReverbLevel, true)
-	addRegister("reverb-time", &ReverbTime, false)
-	addRegister("reverb-delay-feedback", &ReverbDelayFeedback, false)
-	addRegister("reverb-to-chorus-level", &ReverbToChorusLevel, false)
-	addRegister("chorus-macro", &ChorusMacro, false)
-	addRegister("chorus-pre-lpf", &ChorusPreLPF, false)
-	addRegister("chorus-level", &ChorusLevel, true)
-	addRegister("chorus-feedback", &ChorusFeedback, false)
-	addRegister("chorus-delay", &ChorusDelay, false)
-	addRegister("chorus-rate", &ChorusRate, false)
-	addRegister("chorus-depth", &ChorusDepth, false)
-	addRegister("chorus-to-reverb-level", &ChorusToReverbLevel, false)
+	registerTags = make(map[*Register][]Tag)
+	registerModel = make(map[*Register]Model)
+	registerEncoding = make(map[*Register]Encoding)
+	registerValueNames = make(map[*Register][]string)
+	registerUnits = make(map[*Register]string)
+	registerControllers = make(map[*Register]int)
+	registerFormatters = make(map[*Register]Formatter)
+
+	addRegister("master-tune", &MasterTune, TagFrontPanel, TagTuning)
+	registerEncoding[&MasterTune] = EncodingNibbles
+	addRegister("master-volume", &MasterVolume, TagFrontPanel)
+	addRegister("master-key-shift", &MasterKeyShift, TagFrontPanel, TagTuning)
+	addRegister("master-pan", &MasterPan, TagFrontPanel)
+	addRegister("reverb-macro", &ReverbMacro, TagEffects)
+	addRegister("reverb-character", &ReverbCharacter, TagEffects)
+	addRegister("reverb-pre-lpf", &ReverbPreLPF, TagEffects, TagRarelyUsed)
+	addRegister("reverb-level", &ReverbLevel, TagFrontPanel, TagEffects)
+	addRegister("reverb-time", &ReverbTime, TagEffects)
+	addRegister("reverb-delay-feedback", &ReverbDelayFeedback, TagEffects, TagRarelyUsed)
+	addRegister("reverb-to-chorus-level", &ReverbToChorusLevel, TagEffects)
+	addRegister("chorus-macro", &ChorusMacro, TagEffects)
+	addRegister("chorus-pre-lpf", &ChorusPreLPF, TagEffects, TagRarelyUsed)
+	addRegister("chorus-level", &ChorusLevel, TagFrontPanel, TagEffects)
+	addRegister("chorus-feedback", &ChorusFeedback, TagEffects, TagRarelyUsed)
+	addRegister("chorus-delay", &ChorusDelay, TagEffects, TagRarelyUsed)
+	addRegister("chorus-rate", &ChorusRate, TagEffects)
+	addRegister("chorus-depth", &ChorusDepth, TagEffects)
+	addRegister("chorus-to-reverb-level", &ChorusToReverbLevel, TagEffects)
 
 	for i := range parts {
 		// As per the SC-55 manual ... (yes this is silly)
@@ -471,12 +918,36 @@ func init() {
 		// i #11 -> partNumber 12 -> partIndex B
 		// ...
 		// i #15 -> partNumber 16 -> partIndex F
-		partNumber := i + 1
-		prefix := fmt.Sprintf("part-%d.", partNumber)
+		// Parts 17-32 are the same again for port B, at 0x50xxxx.
+		partNumber := i%16 + 1
+		prefix := fmt.Sprintf("part-%d.", i+1)
 		partIndex := (partNumber % 10)
 		if partNumber > 10 {
 			partIndex = partNumber - 1
 		}
-		parts[i].init(prefix, 0x401000+partIndex*0x100)
+		m, base, index := ModelSC55, 0, partIndex
+		if i >= 16 {
+			m, base, index = ModelSC88, portBOffset, partIndex+16
+		}
+		parts[i].init(prefix, base+0x401000+partIndex*0x100, m)
+		// The range allows for the largest model; SetVoiceReserve
+		// checks the total against the current one.
+		voiceReserve[index] = Register{base + 0x400110 + partIndex, 1, 0x00, ModelSC88Pro.MaxVoices(), 0}
+		addRegister(prefix+"voice-reserve", &voiceReserve[index], TagRarelyUsed)
+		registerModel[&voiceReserve[index]] = m
+		sc55mk2Parts[index].init(prefix, base+0x401000+partIndex*0x100, m)
+		sc88Parts[index].init(prefix, base+0x401000+partIndex*0x100)
+	}
+	addSC88Registers()
+	addValueNames()
+	addUnits()
+	addFormatters()
+
+	for m := range drumNotes {
+		for i := range drumNotes[m] {
+			note := DrumNoteMin + i
+			prefix := fmt.Sprintf("drum-%d.note-%d.", m+1, note)
+			drumNotes[m][i].init(prefix, 0x410000+m*0x1000+note)
+		}
 	}
 }
@@ -0,0 +1,181 @@
+package sc55
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPartIndex(t *testing.T) {
+	for i := range parts {
+		if got := parts[i].partIndex(); got < 0 || got >= NumParts {
+			t.Fatalf("part %d: partIndex() = %d, want 0 <= x < %d", i+1, got, NumParts)
+		}
+	}
+	tests := []struct {
+		name string
+		reg  func(p *Part) *Register
+	}{
+		{"voice-reserve", (*Part).VoiceReserve},
+		{"sc55mk2 mod-pitch-control", func(p *Part) *Register { return &p.SC55mk2().ModPitchControl }},
+		{"sc88 eq-switch", func(p *Part) *Register { return &p.SC88().EQSwitch }},
+	}
+	for _, tt := range tests {
+		seen := map[*Register]int{}
+		for i := range parts {
+			r := tt.reg(&parts[i])
+			if other, ok := seen[r]; ok {
+				t.Errorf("%s: parts %d and %d share register %q", tt.name, other, i+1, r.Name())
+			}
+			seen[r] = i + 1
+		}
+		p1, p17 := tt.reg(&parts[0]), tt.reg(&parts[16])
+		if p1.Address+portBOffset != p17.Address {
+			t.Errorf("%s: part 17 address %06x, want part 1 address %06x + %06x", tt.name, p17.Address, p1.Address, portBOffset)
+		}
+	}
+}
+
+func TestSetVoiceReserve(t *testing.T) {
+	voices := [16]int{}
+	voices[0] = 2
+	msg, err := SetVoiceReserve(0x10, voices)
+	if err != nil {
+		t.Fatalf("SetVoiceReserve() failed: %v", err)
+	}
+	_, addr, data, err := UnmarshalSet(msg)
+	if err != nil {
+		t.Fatalf("UnmarshalSet() failed: %v", err)
+	}
+	if addr != voiceReserve[0].Address || len(data) != len(voices) {
+		t.Errorf("SetVoiceReserve() wrote %d bytes at %06x, want %d at %06x", len(data), addr, len(voices), voiceReserve[0].Address)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		encoding Encoding
+		value    int
+		want     []byte
+		wantErr  bool
+	}{
+		{"byte zero", 1, EncodingBytes, 0x00, []byte{0x00}, false},
+		{"byte max", 1, EncodingBytes, 0x7f, []byte{0x7f}, false},
+		{"byte two bytes", 2, EncodingBytes, 0x0102, []byte{0x01, 0x02}, false},
+		{"byte not MIDI data", 1, EncodingBytes, 0x80, nil, true},
+		{"byte too wide", 1, EncodingBytes, 0x100, nil, true},
+		{"byte negative", 1, EncodingBytes, -1, nil, true},
+		{"nibbles master tune center", 4, EncodingNibbles, 0x400, []byte{0x00, 0x04, 0x00, 0x00}, false},
+		{"nibbles all set", 4, EncodingNibbles, 0xffff, []byte{0x0f, 0x0f, 0x0f, 0x0f}, false},
+		{"nibbles two bytes", 2, EncodingNibbles, 0xa5, []byte{0x0a, 0x05}, false},
+		{"nibbles too wide", 4, EncodingNibbles, 0x10000, nil, true},
+		{"nibbles negative", 2, EncodingNibbles, -1, nil, true},
+		{"bad size", 5, EncodingBytes, 0, nil, true},
+	}
+	for _, tt := range tests {
+		r := &Register{Size: tt.size}
+		registerEncoding[r] = tt.encoding
+		got, err := r.encode(tt.value)
+		delete(registerEncoding, r)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: encode(%#x) = % x, want error", tt.name, tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: encode(%#x) failed: %v", tt.name, tt.value, err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: encode(%#x) = % x, want % x", tt.name, tt.value, got, tt.want)
+		}
+		registerEncoding[r] = tt.encoding
+		value, err := r.decode(got)
+		delete(registerEncoding, r)
+		if err != nil || value != tt.value {
+			t.Errorf("%s: decode(% x) = %#x, %v, want %#x", tt.name, got, value, err, tt.value)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		encoding Encoding
+		payload  []byte
+	}{
+		{"too short", 2, EncodingBytes, []byte{0x01}},
+		{"too long", 1, EncodingBytes, []byte{0x01, 0x02}},
+		{"nibble out of range", 4, EncodingNibbles, []byte{0x00, 0x10, 0x00, 0x00}},
+		{"nibble high bit", 2, EncodingNibbles, []byte{0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		r := &Register{Size: tt.size}
+		registerEncoding[r] = tt.encoding
+		if value, err := r.decode(tt.payload); err == nil {
+			t.Errorf("%s: decode(% x) = %#x, want error", tt.name, tt.payload, value)
+		}
+		delete(registerEncoding, r)
+	}
+}
+
+func TestAppendEncodedError(t *testing.T) {
+	// The first byte is valid and the second isn't, so the error is only
+	// found after part of the value has been appended.
+	r := &Register{Size: 2}
+	registerEncoding[r] = EncodingBytes
+	defer delete(registerEncoding, r)
+	dst := []byte{0xaa}
+	got, err := r.appendEncoded(dst, 0x0180)
+	if err == nil {
+		t.Fatalf("appendEncoded(%#x) = % x, want error", 0x0180, got)
+	}
+	if !bytes.Equal(got, dst) {
+		t.Errorf("appendEncoded returned % x on error, want dst unchanged (% x)", got, dst)
+	}
+}
+
+func TestSetUnmarshalRoundTrip(t *testing.T) {
+	for _, r := range []*Register{&MasterTune, &MasterVolume, &MasterPan} {
+		min, max := r.Range()
+		for _, value := range []int{min, max, (min + max) / 2} {
+			msg, err := r.Set(0x10, value)
+			if err != nil {
+				t.Errorf("%s: Set(%d) failed: %v", r.Name(), value, err)
+				continue
+			}
+			if _, got, err := r.Unmarshal(msg); err != nil || got != value {
+				t.Errorf("%s: Unmarshal(Set(%d)) = %d, %v", r.Name(), value, got, err)
+			}
+		}
+		// Out of range values are clamped.
+		msg, err := r.Set(0x10, max+1)
+		if err != nil {
+			t.Errorf("%s: Set(%d) failed: %v", r.Name(), max+1, err)
+			continue
+		}
+		if _, got, err := r.Unmarshal(msg); err != nil || got != max {
+			t.Errorf("%s: Unmarshal(Set(%d)) = %d, %v, want %d", r.Name(), max+1, got, err, max)
+		}
+		if _, err := r.SetStrict(0x10, max+1); err == nil {
+			t.Errorf("%s: SetStrict(%d) succeeded, want error", r.Name(), max+1)
+		}
+	}
+}
+
+func TestSetVoiceReserveMaxVoices(t *testing.T) {
+	defer SetModel(CurrentModel())
+	voices := [16]int{}
+	voices[0], voices[9] = 16, 10
+	SetModel(ModelSC55)
+	if _, err := SetVoiceReserve(0x10, voices); err == nil {
+		t.Errorf("SetVoiceReserve() of 26 voices succeeded on %s, want error", ModelSC55)
+	}
+	SetModel(ModelSC55mk2)
+	if _, err := SetVoiceReserve(0x10, voices); err != nil {
+		t.Errorf("SetVoiceReserve() of 26 voices failed on %s: %v", ModelSC55mk2, err)
+	}
+}
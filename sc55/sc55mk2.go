@@ -0,0 +1,38 @@
+package sc55
+
+// SC55mk2Part holds the part registers that were added by the SC-55mkII:
+// the modulation (CC#1) controller settings, which set how the modulation
+// wheel affects the part's sound. Offsets are relative to the part's block
+// like those of Part, though they live in the following page of memory.
+type SC55mk2Part struct {
+	ModPitchControl   Register `name:"mod-pitch-control" tags:"rarely-used" model:"sc55mk2" units:"semitones"`
+	ModTVFCutoff      Register `name:"mod-tvf-cutoff" tags:"rarely-used" model:"sc55mk2"`
+	ModAmplitude      Register `name:"mod-amplitude" tags:"rarely-used" model:"sc55mk2"`
+	ModLFO1Rate       Register `name:"mod-lfo1-rate" tags:"rarely-used" model:"sc55mk2"`
+	ModLFO1PitchDepth Register `name:"mod-lfo1-pitch-depth" model:"sc55mk2"`
+	ModLFO1TVFDepth   Register `name:"mod-lfo1-tvf-depth" tags:"rarely-used" model:"sc55mk2"`
+	ModLFO1TVADepth   Register `name:"mod-lfo1-tva-depth" tags:"rarely-used" model:"sc55mk2"`
+}
+
+var templateSC55mk2Part = SC55mk2Part{
+	ModPitchControl:   Register{0x1000, 1, 0x28, 0x58, 0x40},
+	ModTVFCutoff:      Register{0x1001, 1, 0x00, 0x7f, 0x40},
+	ModAmplitude:      Register{0x1002, 1, 0x00, 0x7f, 0x40},
+	ModLFO1Rate:       Register{0x1003, 1, 0x00, 0x7f, 0x40},
+	ModLFO1PitchDepth: Register{0x1004, 1, 0x00, 0x7f, 0},
+	ModLFO1TVFDepth:   Register{0x1005, 1, 0x00, 0x7f, 0},
+	ModLFO1TVADepth:   Register{0x1006, 1, 0x00, 0x7f, 0},
+}
+
+var sc55mk2Parts [NumParts]SC55mk2Part
+
+func (p *SC55mk2Part) init(prefix string, addr int, m Model) {
+	*p = templateSC55mk2Part
+	addRegisters(p, prefix, addr, m)
+}
+
+// SC55mk2 returns the part's registers that only exist on the SC-55mkII
+// and later.
+func (p *Part) SC55mk2() *SC55mk2Part {
+	return &sc55mk2Parts[p.partIndex()]
+}
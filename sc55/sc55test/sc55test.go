@@ -0,0 +1,187 @@
+// Package sc55test provides a simulated SoundCanvas that an sc55.Device can
+// talk to, so that programs using the sc55 package can be tested without
+// real hardware. Faults seen on real MIDI links (lost and corrupted
+// messages, slow replies, and replies split over several messages) can be
+// injected to exercise the Device's retry and reassembly logic. Faults are
+// chosen by a seeded random number generator, so tests are repeatable.
+package sc55test
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+)
+
+// Faults configures the faults that a FakeDevice injects.
+type Faults struct {
+	// Loss is the probability that a message is lost, in either
+	// direction.
+	Loss float64
+	// Corruption is the probability that one of the data bytes of a
+	// reply is changed, so that its checksum no longer matches.
+	Corruption float64
+	// Delay is how long it takes for a reply to arrive after the
+	// request is sent.
+	Delay time.Duration
+	// SplitSize, if positive, is the largest number of data bytes in a
+	// reply message; longer replies are split over several messages.
+	SplitSize int
+	// Seed seeds the random number generator that decides which
+	// messages are lost or corrupted.
+	Seed int64
+}
+
+// reply is a message waiting to be read from a FakeDevice.
+type reply struct {
+	when time.Time
+	msg  []byte
+}
+
+// FakeDevice is a simulated SoundCanvas. It implements sc55.Input and
+// sc55.Output, replying to RQ1 requests with the contents of its memory
+// and storing data written by DT1 messages. Like a real device, it ignores
+// messages for other device IDs and doesn't reply to requests for memory
+// that it doesn't implement; memory is implemented by setting it with
+// Poke.
+type FakeDevice struct {
+	mu       sync.Mutex
+	id       sc55.DeviceID
+	faults   Faults
+	rng      *rand.Rand
+	memory   map[int]byte
+	pending  []reply
+	received [][]byte
+}
+
+// New returns a FakeDevice with the given device ID that injects the given
+// faults.
+func New(id sc55.DeviceID, faults Faults) *FakeDevice {
+	return &FakeDevice{
+		id:     id,
+		faults: faults,
+		rng:    rand.New(rand.NewSource(faults.Seed)),
+		memory: map[int]byte{},
+	}
+}
+
+// SetFaults changes the faults that the device injects from now on.
+func (d *FakeDevice) SetFaults(faults Faults) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.faults = faults
+	d.rng = rand.New(rand.NewSource(faults.Seed))
+}
+
+// Poke sets the contents of memory starting at the given address, marking
+// it as implemented.
+func (d *FakeDevice) Poke(addr int, data ...byte) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, b := range data {
+		d.memory[addr+i] = b
+	}
+}
+
+// Peek returns the contents of memory starting at the given address, and
+// false if any of it isn't implemented.
+func (d *FakeDevice) Peek(addr, size int) ([]byte, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.peek(addr, size)
+}
+
+func (d *FakeDevice) peek(addr, size int) ([]byte, bool) {
+	data := make([]byte, size)
+	for i := range data {
+		b, ok := d.memory[addr+i]
+		if !ok {
+			return nil, false
+		}
+		data[i] = b
+	}
+	return data, true
+}
+
+// Received returns the messages that the device has received (not
+// counting any that were lost), in order.
+func (d *FakeDevice) Received() [][]byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]byte{}, d.received...)
+}
+
+// WriteSysEx sends a message to the device.
+func (d *FakeDevice) WriteSysEx(msg []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.lost() {
+		return nil
+	}
+	d.received = append(d.received, append([]byte{}, msg...))
+	if dev, addr, data, err := sc55.UnmarshalSet(msg); err == nil {
+		if dev.Matches(d.id) {
+			d.write(addr, data)
+		}
+		return nil
+	}
+	if dev, addr, size, err := sc55.UnmarshalGet(msg); err == nil && dev.Matches(d.id) {
+		d.request(addr, size)
+	}
+	return nil
+}
+
+// write stores data written by a DT1. Writes to memory that isn't
+// implemented are ignored.
+func (d *FakeDevice) write(addr int, data []byte) {
+	for i, b := range data {
+		if _, ok := d.memory[addr+i]; ok {
+			d.memory[addr+i] = b
+		}
+	}
+}
+
+// request queues the reply to an RQ1.
+func (d *FakeDevice) request(addr, size int) {
+	data, ok := d.peek(addr, size)
+	if !ok {
+		return
+	}
+	split := d.faults.SplitSize
+	if split <= 0 {
+		split = len(data)
+	}
+	when := time.Now().Add(d.faults.Delay)
+	for start := 0; start < len(data); start += split {
+		end := min(start+split, len(data))
+		msg := sc55.DataSet(d.id, addr+start, data[start:end]...)
+		if d.lost() {
+			continue
+		}
+		if d.rng.Float64() < d.faults.Corruption {
+			// Change a data byte, leaving the checksum as it was.
+			i := 8 + d.rng.Intn(end-start)
+			msg[i] ^= 0x01
+		}
+		d.pending = append(d.pending, reply{when, msg})
+	}
+}
+
+// lost returns true if a message should be lost.
+func (d *FakeDevice) lost() bool {
+	return d.rng.Float64() < d.faults.Loss
+}
+
+// ReadSysEx returns the next reply that has arrived, or nil if there is
+// none yet.
+func (d *FakeDevice) ReadSysEx() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.pending) == 0 || time.Now().Before(d.pending[0].when) {
+		return nil, nil
+	}
+	msg := d.pending[0].msg
+	d.pending = d.pending[1:]
+	return msg, nil
+}
@@ -0,0 +1,99 @@
+package sc55test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+)
+
+const testID = sc55.DefaultDevice
+
+// newTestDevice returns a FakeDevice implementing the system block, and an
+// sc55.Device that talks to it.
+func newTestDevice(faults Faults, retries int) (*FakeDevice, *sc55.Device) {
+	fake := New(testID, faults)
+	fake.Poke(sc55.MasterTune.Address, 0x00, 0x04, 0x00, 0x00, 0x64, 0x40, 0x40)
+	for addr := sc55.ReverbMacro.Address; addr <= sc55.ChorusToReverbLevel.Address; addr++ {
+		fake.Poke(addr, 0x00)
+	}
+	d := sc55.NewDevice(fake, fake, testID)
+	d.Timeout = 20 * time.Millisecond
+	d.Retries = retries
+	return fake, d
+}
+
+func TestQueryAndSet(t *testing.T) {
+	fake, d := newTestDevice(Faults{}, 0)
+	ctx := context.Background()
+	if v, err := d.QueryRegister(ctx, &sc55.MasterVolume); err != nil || v != 0x64 {
+		t.Errorf("QueryRegister(master-volume) = %d, %v, want 100", v, err)
+	}
+	if err := d.SetRegister(ctx, &sc55.MasterVolume, 50); err != nil {
+		t.Fatalf("SetRegister(master-volume) failed: %v", err)
+	}
+	if data, _ := fake.Peek(sc55.MasterVolume.Address, 1); !bytes.Equal(data, []byte{50}) {
+		t.Errorf("master volume memory = % x, want 32", data)
+	}
+	if v, err := d.QueryRegister(ctx, &sc55.MasterVolume); err != nil || v != 50 {
+		t.Errorf("QueryRegister(master-volume) = %d, %v, want 50", v, err)
+	}
+	// Memory that isn't implemented is never replied to.
+	if _, err := d.QueryRegister(ctx, &sc55.PartByNumber(1).PanPot); !errors.Is(err, sc55.ErrTimeout) {
+		t.Errorf("QueryRegister of unimplemented register: got %v, want timeout", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		faults  Faults
+		retries int
+		wantErr bool
+	}{
+		{"split", Faults{SplitSize: 3}, 0, false},
+		{"split into single bytes", Faults{SplitSize: 1}, 0, false},
+		{"slow", Faults{Delay: 5 * time.Millisecond}, 0, false},
+		{"too slow", Faults{Delay: 50 * time.Millisecond}, 0, true},
+		{"lossy", Faults{Loss: 0.3, Seed: 1}, 20, false},
+		{"lossy split", Faults{Loss: 0.1, SplitSize: 4, Seed: 2}, 20, false},
+		{"corrupted", Faults{Corruption: 0.5, Seed: 3}, 20, false},
+		{"always lost", Faults{Loss: 1}, 2, true},
+		{"always corrupted", Faults{Corruption: 1}, 2, true},
+	}
+	block := sc55.Block{Address: sc55.MasterTune.Address, Size: 7}
+	want := []byte{0x00, 0x04, 0x00, 0x00, 0x64, 0x40, 0x40}
+	for _, tt := range tests {
+		_, d := newTestDevice(tt.faults, tt.retries)
+		// Enough requests that some of them hit faults.
+		for i := 0; i < 10; i++ {
+			data, err := d.QueryBlock(context.Background(), block)
+			switch {
+			case tt.wantErr && err == nil:
+				t.Errorf("%s: QueryBlock() = % x, want error", tt.name, data)
+			case !tt.wantErr && err != nil:
+				t.Errorf("%s: QueryBlock() failed: %v", tt.name, err)
+			case !tt.wantErr && !bytes.Equal(data, want):
+				t.Errorf("%s: QueryBlock() = % x, want % x", tt.name, data, want)
+			}
+			if tt.wantErr {
+				break
+			}
+		}
+		if s := d.Stats(); !tt.wantErr && tt.faults.Loss+tt.faults.Corruption > 0 && s.Timeouts == 0 {
+			t.Errorf("%s: no retries were needed; choose a different seed", tt.name)
+		}
+	}
+}
+
+func TestOtherDevice(t *testing.T) {
+	fake, _ := newTestDevice(Faults{}, 0)
+	d := sc55.NewDevice(fake, fake, testID+1)
+	d.Timeout = 10 * time.Millisecond
+	if _, err := d.QueryRegister(context.Background(), &sc55.MasterVolume); !errors.Is(err, sc55.ErrTimeout) {
+		t.Errorf("QueryRegister() for another device ID: got %v, want timeout", err)
+	}
+}
@@ -0,0 +1,108 @@
+package sc55
+
+import "fmt"
+
+// SC88Part holds the part registers that were added by the SC-88 and
+// SC-88Pro. Offsets are relative to the part's block (40 1x 00) like those
+// of Part, though the EQ and EFX switches live in the 40 4x 00 block. The
+// addresses are from the part parameter table of the parameter address map
+// in the SC-88Pro owner's manual: EQ switch at 40 4x 20, EFX switch at
+// 40 4x 22.
+type SC88Part struct {
+	DelaySendLevel Register `name:"delay-send-level" tags:"effects" model:"sc88"`
+	EQSwitch       Register `name:"eq-switch" tags:"effects" model:"sc88" values:"switch"`
+	EFXSwitch      Register `name:"efx-switch" tags:"effects" model:"sc88pro" values:"switch"`
+}
+
+var templateSC88Part = SC88Part{
+	DelaySendLevel: Register{0x002c, 1, 0x00, 0x7f, 0},
+	EQSwitch:       Register{0x3020, 1, 0x00, 0x01, 0},
+	EFXSwitch:      Register{0x3022, 1, 0x00, 0x01, 0},
+}
+
+// SC-88 system registers.
+var (
+	ReverbPreDelay      = Register{0x400137, 1, 0x00, 0x7f, 0}
+	ChorusToDelayLevel  = Register{0x400140, 1, 0x00, 0x7f, 0}
+	DelayMacro          = Register{0x400150, 1, 0x00, 0x07, 0}
+	DelayPreLPF         = Register{0x400151, 1, 0x00, 0x07, 0}
+	DelayTimeCenter     = Register{0x400152, 1, 0x01, 0x73, 0}
+	DelayTimeRatioLeft  = Register{0x400153, 1, 0x01, 0x78, 0}
+	DelayTimeRatioRight = Register{0x400154, 1, 0x01, 0x78, 0}
+	DelayLevelCenter    = Register{0x400155, 1, 0x00, 0x7f, 0}
+	DelayLevelLeft      = Register{0x400156, 1, 0x00, 0x7f, 0}
+	DelayLevelRight     = Register{0x400157, 1, 0x00, 0x7f, 0}
+	DelayLevel          = Register{0x400158, 1, 0x00, 0x7f, 0}
+	DelayFeedback       = Register{0x400159, 1, 0x00, 0x7f, 0x40}
+	DelayToReverbLevel  = Register{0x40015a, 1, 0x00, 0x7f, 0}
+	EQLowFreq           = Register{0x400200, 1, 0x00, 0x01, 0}
+	EQLowGain           = Register{0x400201, 1, 0x34, 0x4c, 0x40}
+	EQHighFreq          = Register{0x400202, 1, 0x00, 0x01, 0}
+	EQHighGain          = Register{0x400203, 1, 0x34, 0x4c, 0x40}
+)
+
+// SC-88Pro insertion effect (EFX) registers.
+var (
+	EFXType           = Register{0x400300, 2, 0x0000, 0x7f7f, 0}
+	EFXParameters     [20]Register
+	EFXToReverbLevel  = Register{0x400317, 1, 0x00, 0x7f, 0}
+	EFXToChorusLevel  = Register{0x400318, 1, 0x00, 0x7f, 0}
+	EFXToDelayLevel   = Register{0x400319, 1, 0x00, 0x7f, 0}
+	EFXControlSource1 = Register{0x40031b, 1, 0x00, 0x65, 0}
+	EFXControlDepth1  = Register{0x40031c, 1, 0x00, 0x7f, 0x40}
+	EFXControlSource2 = Register{0x40031d, 1, 0x00, 0x65, 0}
+	EFXControlDepth2  = Register{0x40031e, 1, 0x00, 0x7f, 0x40}
+	EFXSendEQSwitch   = Register{0x40031f, 1, 0x00, 0x01, 0}
+
+	sc88Parts [NumParts]SC88Part
+)
+
+func (p *SC88Part) init(prefix string, addr int) {
+	*p = templateSC88Part
+	addRegisters(p, prefix, addr, ModelSC88)
+}
+
+// SC88 returns the part's registers that only exist on the SC-88 and
+// later.
+func (p *Part) SC88() *SC88Part {
+	return &sc88Parts[p.partIndex()]
+}
+
+func addModelRegister(name string, r *Register, m Model) {
+	addRegister(name, r, TagEffects)
+	registerModel[r] = m
+}
+
+func addSC88Registers() {
+	addModelRegister("reverb-pre-delay", &ReverbPreDelay, ModelSC88)
+	addModelRegister("chorus-to-delay-level", &ChorusToDelayLevel, ModelSC88)
+	addModelRegister("delay-macro", &DelayMacro, ModelSC88)
+	addModelRegister("delay-pre-lpf", &DelayPreLPF, ModelSC88)
+	addModelRegister("delay-time-center", &DelayTimeCenter, ModelSC88)
+	addModelRegister("delay-time-ratio-left", &DelayTimeRatioLeft, ModelSC88)
+	addModelRegister("delay-time-ratio-right", &DelayTimeRatioRight, ModelSC88)
+	addModelRegister("delay-level-center", &DelayLevelCenter, ModelSC88)
+	addModelRegister("delay-level-left", &DelayLevelLeft, ModelSC88)
+	addModelRegister("delay-level-right", &DelayLevelRight, ModelSC88)
+	addModelRegister("delay-level", &DelayLevel, ModelSC88)
+	addModelRegister("delay-feedback", &DelayFeedback, ModelSC88)
+	addModelRegister("delay-to-reverb-level", &DelayToReverbLevel, ModelSC88)
+	addModelRegister("eq-low-freq", &EQLowFreq, ModelSC88)
+	addModelRegister("eq-low-gain", &EQLowGain, ModelSC88)
+	addModelRegister("eq-high-freq", &EQHighFreq, ModelSC88)
+	addModelRegister("eq-high-gain", &EQHighGain, ModelSC88)
+
+	addModelRegister("efx-type", &EFXType, ModelSC88Pro)
+	for i := range EFXParameters {
+		EFXParameters[i] = Register{0x400303 + i, 1, 0x00, 0x7f, 0}
+		addModelRegister(fmt.Sprintf("efx-parameter-%d", i+1), &EFXParameters[i], ModelSC88Pro)
+	}
+	addModelRegister("efx-to-reverb-level", &EFXToReverbLevel, ModelSC88Pro)
+	addModelRegister("efx-to-chorus-level", &EFXToChorusLevel, ModelSC88Pro)
+	addModelRegister("efx-to-delay-level", &EFXToDelayLevel, ModelSC88Pro)
+	addModelRegister("efx-control-source-1", &EFXControlSource1, ModelSC88Pro)
+	addModelRegister("efx-control-depth-1", &EFXControlDepth1, ModelSC88Pro)
+	addModelRegister("efx-control-source-2", &EFXControlSource2, ModelSC88Pro)
+	addModelRegister("efx-control-depth-2", &EFXControlDepth2, ModelSC88Pro)
+	addModelRegister("efx-send-eq-switch", &EFXSendEQSwitch, ModelSC88Pro)
+}
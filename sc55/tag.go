@@ -0,0 +1,78 @@
+package sc55
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag is a label used to group related registers, eg. so that a program can
+// show only the effect settings.
+type Tag string
+
+const (
+	// TagFrontPanel marks settings shown on the physical front panel of
+	// the device.
+	TagFrontPanel = Tag("front-panel")
+	// TagEffects marks reverb, chorus, delay, EQ and EFX settings.
+	TagEffects = Tag("effects")
+	// TagRouting marks settings that control which messages a part
+	// receives and which notes it plays.
+	TagRouting = Tag("routing")
+	// TagTuning marks pitch and key shift settings.
+	TagTuning = Tag("tuning")
+	// TagRarelyUsed marks obscure settings that most users never change.
+	TagRarelyUsed = Tag("rarely-used")
+)
+
+var allTags = []Tag{TagFrontPanel, TagEffects, TagRouting, TagTuning, TagRarelyUsed}
+
+// AllTags returns all the register tags.
+func AllTags() []Tag {
+	return append([]Tag{}, allTags...)
+}
+
+// ParseTags parses a comma-separated list of tag names.
+func ParseTags(s string) ([]Tag, error) {
+	result := []Tag{}
+	for _, name := range strings.Split(s, ",") {
+		t := Tag(strings.TrimSpace(name))
+		if t == "" {
+			continue
+		}
+		known := false
+		for _, u := range allTags {
+			known = known || t == u
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown tag %q", t)
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// Tags returns the tags of the given register.
+func (r *Register) Tags() []Tag {
+	return append([]Tag{}, registerTags[r]...)
+}
+
+// HasTag returns true if the given register has the given tag.
+func (r *Register) HasTag(t Tag) bool {
+	for _, u := range registerTags[r] {
+		if u == t {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAnyTag returns true if the given register has at least one of the
+// given tags.
+func (r *Register) HasAnyTag(tags []Tag) bool {
+	for _, t := range tags {
+		if r.HasTag(t) {
+			return true
+		}
+	}
+	return false
+}
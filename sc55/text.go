@@ -0,0 +1,119 @@
+package sc55
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// TextRegister is a region of SoundCanvas memory that holds a fixed-length
+// string of printable ASCII, such as the patch name. Unlike a Register, its
+// value is a string rather than a number.
+type TextRegister struct {
+	Address, Size int
+}
+
+var (
+	PatchName = TextRegister{0x400100, 16}
+
+	drumMapNames [NumDrumMaps]TextRegister
+
+	textRegistersByName map[string]*TextRegister
+	textRegisterName    map[*TextRegister]string
+)
+
+func addTextRegister(name string, t *TextRegister) {
+	textRegistersByName[name] = t
+	textRegisterName[t] = name
+}
+
+func init() {
+	textRegistersByName = make(map[string]*TextRegister)
+	textRegisterName = make(map[*TextRegister]string)
+
+	addTextRegister("patch-name", &PatchName)
+	for m := range drumMapNames {
+		drumMapNames[m] = TextRegister{0x410000 + m*0x1000, 12}
+		addTextRegister(fmt.Sprintf("drum-%d.map-name", m+1), &drumMapNames[m])
+	}
+}
+
+// Name returns the name of the text register.
+func (t *TextRegister) Name() string {
+	return textRegisterName[t]
+}
+
+// Block returns the block of memory holding the text register's contents.
+func (t *TextRegister) Block() Block {
+	return Block{t.Address, t.Size}
+}
+
+// Get returns an SC-55 SysEx command to get the contents of the register.
+func (t *TextRegister) Get(device DeviceID) []byte {
+	return DataGet(device, t.Address, t.Size)
+}
+
+// Set returns an SC-55 SysEx command that sets the register to the given
+// string, padded with spaces to the register's size. An error is returned
+// if the string is too long or contains anything other than printable
+// ASCII.
+func (t *TextRegister) Set(device DeviceID, s string) ([]byte, error) {
+	if len(s) > t.Size {
+		return nil, fmt.Errorf("%q is longer than %d characters", s, t.Size)
+	}
+	for i := 0; i < len(s); i++ {
+		if !isPrintableASCII(s[i]) {
+			return nil, fmt.Errorf("%q contains a character that isn't printable ASCII", s)
+		}
+	}
+	data := append([]byte(s), bytes.Repeat([]byte{' '}, t.Size-len(s))...)
+	return DataSet(device, t.Address, data...), nil
+}
+
+func isPrintableASCII(b byte) bool {
+	return b >= 0x20 && b <= 0x7e
+}
+
+// Decode returns the string contained in the raw bytes of the register,
+// with any trailing padding removed.
+func (t *TextRegister) Decode(payload []byte) (string, error) {
+	if len(payload) != t.Size {
+		return "", fmt.Errorf("wrong size: want %d bytes, got %d", t.Size, len(payload))
+	}
+	payload = bytes.TrimRight(payload, " \x00")
+	for _, b := range payload {
+		if !isPrintableASCII(b) {
+			return "", fmt.Errorf("byte %02x is not a printable ASCII character", b)
+		}
+	}
+	return string(payload), nil
+}
+
+// DrumMapName returns the text register holding the name of the given drum
+// map (1 or 2), or nil if there is no such drum map.
+func DrumMapName(drumMap int) *TextRegister {
+	if drumMap < 1 || drumMap > NumDrumMaps {
+		return nil
+	}
+	return &drumMapNames[drumMap-1]
+}
+
+// TextRegisterByName looks up a text register by name, returning register,
+// true if it exists or nil, false if there is no such register.
+func TextRegisterByName(name string) (*TextRegister, bool) {
+	t, ok := textRegistersByName[name]
+	return t, ok
+}
+
+// AllTextRegisters returns a slice containing all known text registers,
+// sorted by address.
+func AllTextRegisters() []*TextRegister {
+	result := []*TextRegister{}
+	for _, t := range textRegistersByName {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Address < result[j].Address
+	})
+	return result
+}
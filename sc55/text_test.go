@@ -0,0 +1,59 @@
+package sc55
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextRegisterSet(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []byte
+		wantErr bool
+	}{
+		{"Piano", []byte("Piano           "), false},
+		{"~{}\\", []byte("~{}\\            "), false},
+		{"", bytes.Repeat([]byte{' '}, 16), false},
+		{"0123456789abcdef", []byte("0123456789abcdef"), false},
+		{"0123456789abcdefg", nil, true},
+		{"Café", nil, true},
+		{"ｶﾀｶﾅ", nil, true},
+		{"tab\there", nil, true},
+		{"\x0e\x31\x0f", nil, true},
+	}
+	for _, tt := range tests {
+		msg, err := PatchName.Set(0x10, tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Set(%q) = % x, want error", tt.in, msg)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Set(%q) failed: %v", tt.in, err)
+			continue
+		}
+		_, _, data, err := UnmarshalSet(msg)
+		if err != nil {
+			t.Errorf("UnmarshalSet(Set(%q)) failed: %v", tt.in, err)
+			continue
+		}
+		if !bytes.Equal(data, tt.want) {
+			t.Errorf("Set(%q) data = %q, want %q", tt.in, data, tt.want)
+		}
+		if got, err := PatchName.Decode(data); err != nil || got != tt.in {
+			t.Errorf("Decode(%q) = %q, %v, want %q", data, got, err, tt.in)
+		}
+	}
+}
+
+func TestTextRegisterDecodeInvalid(t *testing.T) {
+	for _, payload := range [][]byte{
+		[]byte("short"),
+		append([]byte("Piano\x0e\x31\x0f"), bytes.Repeat([]byte{' '}, 8)...),
+	} {
+		if got, err := PatchName.Decode(payload); err == nil {
+			t.Errorf("Decode(%q) = %q, want error", payload, got)
+		}
+	}
+}
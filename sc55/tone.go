@@ -0,0 +1,134 @@
+package sc55
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tone is an instrument in the GS tone map, selected with a bank select
+// (CC#0, the variation number) followed by a program change.
+type Tone struct {
+	Name string
+	// Bank is the variation number; 0 for the capital tones.
+	Bank int
+	// Program is the program number in the range 1-128, as shown in the
+	// manual and on the front panel.
+	Program int
+}
+
+// capitalTones are the names of the 128 capital tones, in program order.
+var capitalTones = [128]string{
+	"Piano 1", "Piano 2", "Piano 3", "Honky-tonk",
+	"E.Piano 1", "E.Piano 2", "Harpsichord", "Clav.",
+	"Celesta", "Glockenspiel", "Music Box", "Vibraphone",
+	"Marimba", "Xylophone", "Tubular-bell", "Santur",
+	"Organ 1", "Organ 2", "Organ 3", "Church Org.1",
+	"Reed Organ", "Accordion Fr", "Harmonica", "Bandneon",
+	"Nylon-str.Gt", "Steel-str.Gt", "Jazz Gt.", "Clean Gt.",
+	"Muted Gt.", "Overdrive Gt", "DistortionGt", "Gt.Harmonics",
+	"Acoustic Bs.", "Fingered Bs.", "Picked Bs.", "Fretless Bs.",
+	"Slap Bass 1", "Slap Bass 2", "Synth Bass 1", "Synth Bass 2",
+	"Violin", "Viola", "Cello", "Contrabass",
+	"Tremolo Str", "PizzicatoStr", "Harp", "Timpani",
+	"Strings", "Slow Strings", "Syn.Strings1", "Syn.Strings2",
+	"Choir Aahs", "Voice Oohs", "SynVox", "OrchestraHit",
+	"Trumpet", "Trombone", "Tuba", "MutedTrumpet",
+	"French Horn", "Brass 1", "Synth Brass1", "Synth Brass2",
+	"Soprano Sax", "Alto Sax", "Tenor Sax", "Baritone Sax",
+	"Oboe", "English Horn", "Bassoon", "Clarinet",
+	"Piccolo", "Flute", "Recorder", "Pan Flute",
+	"Bottle Blow", "Shakuhachi", "Whistle", "Ocarina",
+	"Square Wave", "Saw Wave", "Syn.Calliope", "Chiffer Lead",
+	"Charang", "Solo Vox", "5th Saw Wave", "Bass & Lead",
+	"Fantasia", "Warm Pad", "Polysynth", "Space Voice",
+	"Bowed Glass", "Metal Pad", "Halo Pad", "Sweep Pad",
+	"Ice Rain", "Soundtrack", "Crystal", "Atmosphere",
+	"Brightness", "Goblin", "Echo Drops", "Star Theme",
+	"Sitar", "Banjo", "Shamisen", "Koto",
+	"Kalimba", "Bag Pipe", "Fiddle", "Shanai",
+	"Tinkle Bell", "Agogo", "Steel Drums", "Woodblock",
+	"Taiko", "Melo. Tom 1", "Synth Drum", "Reverse Cym.",
+	"Gt.FretNoise", "Breath Noise", "Seashore", "Bird",
+	"Telephone 1", "Helicopter", "Applause", "Gun Shot",
+}
+
+// variationTones are some commonly used variation tones. The full GS map
+// has many more; any tone can still be selected by number.
+var variationTones = []Tone{
+	{"Piano 1w", 8, 1},
+	{"Detuned EP 1", 8, 5},
+	{"Detuned EP 2", 8, 6},
+	{"Coupled Hps.", 8, 7},
+	{"Detuned Or.1", 8, 17},
+	{"Detuned Or.2", 8, 18},
+	{"Church Org.2", 8, 20},
+	{"Ukulele", 8, 25},
+	{"12-str.Gt", 8, 26},
+	{"Chorus Gt.", 8, 28},
+	{"Funk Gt.", 8, 29},
+	{"Feedback Gt.", 8, 31},
+	{"Synth Bass 3", 8, 39},
+	{"Synth Bass 4", 8, 40},
+	{"Orchestra", 8, 49},
+	{"Brass 2", 8, 62},
+	{"Synth Brass3", 8, 63},
+	{"Synth Brass4", 8, 64},
+	{"Sine Wave", 8, 81},
+	{"Castanets", 8, 116},
+	{"Concert BD", 8, 117},
+	{"Melo. Tom 2", 8, 118},
+}
+
+// Tones returns the tones in the built-in tone table: all the capital
+// tones, followed by a selection of variation tones.
+func Tones() []Tone {
+	result := []Tone{}
+	for i, name := range capitalTones {
+		result = append(result, Tone{name, 0, i + 1})
+	}
+	return append(result, variationTones...)
+}
+
+// normalizeToneName strips everything but letters and digits so that tone
+// names can be given loosely, eg. "warm pad" or "tubular bell".
+func normalizeToneName(name string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	return sb.String()
+}
+
+// ToneByName looks up a tone in the built-in table by name, ignoring case,
+// spaces and punctuation. It returns tone, true if found or an empty Tone,
+// false if there is no such tone.
+func ToneByName(name string) (Tone, bool) {
+	want := normalizeToneName(name)
+	for _, t := range Tones() {
+		if normalizeToneName(t.Name) == want {
+			return t, true
+		}
+	}
+	return Tone{}, false
+}
+
+// SelectTone returns the channel messages that select the given tone on a
+// MIDI channel (1-16): a bank select (CC#0) for the variation number,
+// followed by a program change.
+func SelectTone(channel int, t Tone) ([][]byte, error) {
+	switch {
+	case channel < 1 || channel > 16:
+		return nil, fmt.Errorf("invalid channel %d, want 1 <= x <= 16", channel)
+	case t.Bank < 0 || t.Bank > 0x7f:
+		return nil, fmt.Errorf("invalid bank %d, want 0 <= x <= 127", t.Bank)
+	case t.Program < 1 || t.Program > 128:
+		return nil, fmt.Errorf("invalid program %d, want 1 <= x <= 128", t.Program)
+	}
+	ch := byte(channel - 1)
+	return [][]byte{
+		{0xb0 | ch, 0x00, byte(t.Bank)},
+		{0xc0 | ch, byte(t.Program - 1)},
+	}, nil
+}
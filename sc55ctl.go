@@ -3,9 +3,10 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"image/png"
 	"log"
 	"os"
 	"strconv"
@@ -20,11 +21,50 @@ import (
 var (
 	midiDevice   string
 	sc55DeviceID int
+	useEmulator  bool
+	readOnly     bool
+	strict       bool
+	bufferSize   int
+	latency      time.Duration
+	maxSysExSize int
+	retries      int
+	adaptive     bool
+	showStats    bool
+	// modelGiven is true if -model was given, rather than left to the
+	// config file.
+	modelGiven bool
 )
 
+// errReadOnly is returned when trying to send a message that would change
+// the state of the device while -read_only is set.
+var errReadOnly = errors.New("refusing to change device state in read-only mode")
+
+// errNoDisplay is returned when trying to send a message to the front
+// panel of a product that doesn't have one.
+type errNoDisplay sc55.Profile
+
+func (e errNoDisplay) Error() string {
+	return fmt.Sprintf("the %s has no display (choose another -model if this is wrong)", e.Description)
+}
+
 func setCommonFlags(f *flag.FlagSet) {
 	f.StringVar(&midiDevice, "midi_device", "", "Name of output MIDI device")
-	f.IntVar(&sc55DeviceID, "sc55_device_id", int(sc55.DefaultDevice), "ID of SC-55 device to control")
+	f.BoolVar(&useEmulator, "emulator", false, "Locate the MIDI port of a software emulator (Munt, Nuked SC-55) automatically")
+	f.IntVar(&sc55DeviceID, "sc55_device_id", int(sc55.DefaultDevice), fmt.Sprintf("ID of SC-55 device to control; %d addresses every device on the bus", sc55.BroadcastDevice))
+	f.IntVar(&bufferSize, "buffer_size", 1024, "size of the portmidi stream buffers, in events; increase if large bulk transfers overflow")
+	f.DurationVar(&latency, "latency", 0, "output latency for portmidi to buffer messages by; 0 sends them immediately")
+	f.IntVar(&maxSysExSize, "max_sysex_size", defaultMaxSysExSize, "largest SysEx message to receive, in bytes; longer messages are dropped, and 0 means no limit")
+	f.IntVar(&retries, "retries", 0, "number of times to resend a request if the SoundCanvas doesn't reply")
+	f.BoolVar(&adaptive, "adaptive_timeout", false, "once replies have been received, wait only a few times the slowest reply time before giving up, doubling on each retry")
+}
+
+// newStream opens a portmidi stream on the given port with the buffer size
+// and latency chosen by the common flags.
+func newStream(id portmidi.DeviceID, output bool) (*portmidi.Stream, error) {
+	if output {
+		return portmidi.NewOutputStream(id, int64(bufferSize), latency.Milliseconds())
+	}
+	return portmidi.NewInputStream(id, int64(bufferSize))
 }
 
 func deviceID() sc55.DeviceID {
@@ -51,28 +91,81 @@ func portForName(name string, output bool) (portmidi.DeviceID, error) {
 	return portmidi.DeviceID(-1), fmt.Errorf("invalid port %q: valid ports: %v", name, strings.Join(portNames, "; "))
 }
 
+// selectedPort returns the ID of the port chosen by the common flags.
+func selectedPort(output bool) (portmidi.DeviceID, error) {
+	switch {
+	case midiDevice != "":
+		return portForName(midiDevice, output)
+	case useEmulator:
+		return emulatorPort(output)
+	case output:
+		return portmidi.DefaultOutputDeviceID(), nil
+	default:
+		return portmidi.DefaultInputDeviceID(), nil
+	}
+}
+
 func openOutputStream() (*portmidi.Stream, error) {
-	id := portmidi.DefaultOutputDeviceID()
-	if midiDevice != "" {
-		var err error
-		id, err = portForName(midiDevice, true)
-		if err != nil {
-			return nil, err
-		}
+	id, err := selectedPort(true)
+	if err != nil {
+		return nil, err
 	}
-	return portmidi.NewOutputStream(id, 1024, 0)
+	return newStream(id, true)
 }
 
 func openInputStream() (*portmidi.Stream, error) {
-	id := portmidi.DefaultInputDeviceID()
-	if midiDevice != "" {
+	id, err := selectedPort(false)
+	if err != nil {
+		return nil, err
+	}
+	return newStream(id, false)
+}
+
+// setRegister returns a message setting the given register to the given
+// value, which is clamped to its range unless -strict is set.
+func setRegister(r *sc55.Register, device sc55.DeviceID, value int) ([]byte, error) {
+	if strict {
+		return r.SetStrict(device, value)
+	}
+	return r.Set(device, value)
+}
+
+// isQuery returns true if the given message only requests data from the
+// device (ie. is an RQ1 or identity request) rather than changing its state.
+func isQuery(msg []byte) bool {
+	t := sc55.Classify(msg)
+	return t == sc55.MessageRQ1 || t == sc55.MessageIdentityRequest
+}
+
+// writeSysEx sends a SysEx message to the given output stream. Everything
+// sent to the device goes through here so that -read_only is enforced, and
+// so that display messages aren't sent to products with no display.
+func writeSysEx(out *portmidi.Stream, msg []byte) error {
+	if readOnly && !isQuery(msg) {
+		return errReadOnly
+	}
+	if p := sc55.CurrentProfile(); !p.Display && sc55.Classify(msg) == sc55.MessageDisplay {
+		return errNoDisplay(p)
+	}
+	return out.WriteSysExBytes(portmidi.Time(), msg)
+}
+
+// openPort opens the named port, or the default port if name is empty.
+func openPort(name string, output bool) (*portmidi.Stream, error) {
+	var id portmidi.DeviceID
+	switch {
+	case name != "":
 		var err error
-		id, err = portForName(midiDevice, false)
+		id, err = portForName(name, output)
 		if err != nil {
 			return nil, err
 		}
+	case output:
+		id = portmidi.DefaultOutputDeviceID()
+	default:
+		id = portmidi.DefaultInputDeviceID()
 	}
-	return portmidi.NewInputStream(id, 1024)
+	return newStream(id, output)
 }
 
 func onlyImportant(regs []*sc55.Register) []*sc55.Register {
@@ -85,8 +178,78 @@ func onlyImportant(regs []*sc55.Register) []*sc55.Register {
 	return important
 }
 
+// selectRegisters returns the registers that the list and get commands
+// operate on when no register is named: registers in any of the given
+// comma-separated groups and with any of the given comma-separated tags if
+// either are given, otherwise all registers or only the important ones.
+func selectRegisters(all bool, tags, groups string) ([]*sc55.Register, error) {
+	regs := sc55.AllRegisters()
+	if groups != "" {
+		want, err := sc55.ParseGroups(groups)
+		if err != nil {
+			return nil, err
+		}
+		result := []*sc55.Register{}
+		for _, r := range regs {
+			if r.InGroup(want) {
+				result = append(result, r)
+			}
+		}
+		regs = result
+		all = true
+	}
+	if tags != "" {
+		want, err := sc55.ParseTags(tags)
+		if err != nil {
+			return nil, err
+		}
+		result := []*sc55.Register{}
+		for _, r := range regs {
+			if r.HasAnyTag(want) {
+				result = append(result, r)
+			}
+		}
+		return result, nil
+	}
+	if !all {
+		regs = onlyImportant(regs)
+	}
+	return regs, nil
+}
+
+// profileNames returns the names of the profiles that can be chosen with
+// -model.
+func profileNames() []string {
+	result := []string{}
+	for _, p := range sc55.AllProfiles() {
+		result = append(result, p.Name)
+	}
+	return result
+}
+
+// groupNames returns the names of the given groups, for display.
+func groupNames(groups []sc55.Group) []string {
+	result := []string{}
+	for _, g := range groups {
+		result = append(result, string(g))
+	}
+	return result
+}
+
+// tagNames returns the names of the given tags, for display.
+func tagNames(tags []sc55.Tag) []string {
+	result := []string{}
+	for _, t := range tags {
+		result = append(result, string(t))
+	}
+	return result
+}
+
 type listRegistersCommand struct {
-	all bool
+	all      bool
+	tags     string
+	groups   string
+	jsonList bool
 }
 
 func (*listRegistersCommand) Name() string     { return "list" }
@@ -95,15 +258,33 @@ func (*listRegistersCommand) Usage() string    { return "" }
 
 func (c *listRegistersCommand) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.all, "all", false, "list all registers")
+	f.StringVar(&c.tags, "tags", "", "only list registers with any of these comma-separated tags ("+strings.Join(tagNames(sc55.AllTags()), ", ")+")")
+	f.StringVar(&c.groups, "group", "", "only list registers in any of these comma-separated groups ("+strings.Join(groupNames(sc55.AllGroups()), ", ")+")")
+	f.BoolVar(&c.jsonList, "json", false, "print the list as a JSON catalog")
 }
 
 func (c *listRegistersCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
-	regs := sc55.AllRegisters()
-	if !c.all {
-		regs = onlyImportant(regs)
+	regs, err := selectRegisters(c.all, c.tags, c.groups)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if c.jsonList {
+		catalog := []sc55.RegisterInfo{}
+		for _, r := range regs {
+			catalog = append(catalog, r.Info())
+		}
+		data, err := json.MarshalIndent(catalog, "", "  ")
+		if err != nil {
+			log.Printf("failed to marshal catalog: %v", err)
+			return subcommands.ExitFailure
+		}
+		fmt.Println(string(data))
+		return subcommands.ExitSuccess
 	}
 	for _, r := range regs {
-		fmt.Printf("% 8x  %s\n", r.Address, r.Name())
+		min, max := r.Range()
+		fmt.Printf("% 8x  %-30s  %6s .. %s\n", r.Address, r.Name(), r.FormatValue(min), r.FormatValue(max))
 	}
 	return subcommands.ExitSuccess
 }
@@ -111,6 +292,9 @@ func (c *listRegistersCommand) Execute(context.Context, *flag.FlagSet, ...interf
 type getRegisterCommand struct {
 	timeout time.Duration
 	all     bool
+	tags    string
+	groups  string
+	bulk    bool
 }
 
 func (*getRegisterCommand) Name() string     { return "get" }
@@ -121,40 +305,126 @@ func (c *getRegisterCommand) SetFlags(f *flag.FlagSet) {
 	setCommonFlags(f)
 	f.DurationVar(&c.timeout, "timeout", 100*time.Millisecond, "how long to wait for a reply from the SoundCanvas before timing out")
 	f.BoolVar(&c.all, "all", false, "fetch values of all registers")
+	f.StringVar(&c.tags, "tags", "", "only fetch registers with any of these comma-separated tags")
+	f.StringVar(&c.groups, "group", "", "only fetch registers in any of these comma-separated groups")
+	f.BoolVar(&c.bulk, "bulk", true, "read whole blocks of memory at once instead of making a request per register")
 }
 
-func (c *getRegisterCommand) queryRegister(in, out *portmidi.Stream, r *sc55.Register) (int, error) {
-	msg := r.Get(deviceID())
-	if err := out.WriteSysExBytes(portmidi.Time(), msg); err != nil {
-		return 0, err
+// defaultMaxSysExSize is the default for -max_sysex_size. It is large
+// enough for any single message the SoundCanvas sends.
+const defaultMaxSysExSize = 1024
+
+// errSysExTruncated is returned when a SysEx message was received that was
+// longer than -max_sysex_size, so that it was lost rather than silently cut
+// short.
+var errSysExTruncated = fmt.Errorf("received a SysEx message longer than -max_sysex_size; it was dropped")
+
+// readSysEx returns the next SysEx message from the input stream, or nil if
+// nothing has been received.
+func readSysEx(in *portmidi.Stream) ([]byte, error) {
+	msg, err := in.ReadSysEx(maxSysExSize)
+	if err == portmidi.ErrSysExOverflow {
+		return nil, errSysExTruncated
 	}
-	timeoutTime := time.Now().Add(c.timeout)
-	for {
-		reply, err := in.ReadSysExBytes(1000)
-		if err != nil {
-			return 0, err
-		}
-		if len(reply) == 0 {
-			if time.Now().After(timeoutTime) {
-				return 0, fmt.Errorf("timeout waiting for reply fetching register %q value", r.Name())
-			}
-			time.Sleep(time.Millisecond)
-			continue
-		}
-		for len(reply) > 0 && reply[len(reply)-1] == 0 {
-			reply = reply[:len(reply)-1]
-		}
-		dev, value, err := r.Unmarshal(reply)
-		if err == nil && dev == deviceID() {
-			return value, nil
-		}
+	return msg, err
+}
+
+// streamInput and streamOutput adapt portmidi streams for use by an
+// sc55.Device. Writes go through writeSysEx so that -read_only applies.
+type streamInput struct{ in *portmidi.Stream }
+type streamOutput struct{ out *portmidi.Stream }
+
+func (s streamInput) ReadSysEx() ([]byte, error)   { return readSysEx(s.in) }
+func (s streamOutput) WriteSysEx(msg []byte) error { return writeSysEx(s.out, msg) }
+
+// deviceKey identifies a device reached through a pair of streams.
+type deviceKey struct {
+	in, out *portmidi.Stream
+	id      sc55.DeviceID
+}
+
+// devices holds the sc55.Device for each device that has been queried, so
+// that reply times measured by one query are used by the next (see
+// -adaptive_timeout) and shown by -stats.
+var devices = map[deviceKey]*sc55.Device{}
+
+// newDevice returns an sc55.Device for the given streams that waits up to
+// the given timeout for each reply, retrying as set by -retries.
+func newDevice(in, out *portmidi.Stream, device sc55.DeviceID, timeout time.Duration) *sc55.Device {
+	key := deviceKey{in, out, device}
+	d, ok := devices[key]
+	if !ok {
+		d = sc55.NewDevice(streamInput{in}, streamOutput{out}, device)
+		devices[key] = d
+	}
+	d.Timeout = timeout
+	d.Retries = retries
+	d.AdaptiveTimeout = adaptive
+	d.Strict = strict
+	return d
+}
+
+// logStats logs the request statistics of every device queried.
+func logStats() {
+	for key, d := range devices {
+		s := d.Stats()
+		log.Printf("device %02x: %d requests, %d timeouts; reply latency min %v, mean %v, max %v",
+			key.id, s.Requests, s.Timeouts, s.MinLatency, s.MeanLatency(), s.MaxLatency)
 	}
 }
 
+// queryRegister sends an RQ1 for the given register and waits for the reply
+// from the given device.
+func queryRegister(in, out *portmidi.Stream, device sc55.DeviceID, r *sc55.Register, timeout time.Duration) (int, error) {
+	return newDevice(in, out, device, timeout).QueryRegister(context.Background(), r)
+}
+
+// queryBlock sends an RQ1 for a whole block of memory and collects the
+// reply, which may be split over several DT1 messages.
+func queryBlock(in, out *portmidi.Stream, device sc55.DeviceID, b sc55.Block, timeout time.Duration) ([]byte, error) {
+	return newDevice(in, out, device, timeout).QueryBlock(context.Background(), b)
+}
+
+// queryRegisters fetches the values of the given registers, reading whole
+// blocks of memory at once rather than making a request per register.
+// Errors are reported per register.
+func queryRegisters(in, out *portmidi.Stream, device sc55.DeviceID, regs []*sc55.Register, timeout time.Duration) (map[*sc55.Register]int, map[*sc55.Register]error) {
+	return newDevice(in, out, device, timeout).QueryRegisters(context.Background(), regs)
+}
+
+// getText prints the contents of a text register.
+func (c *getRegisterCommand) getText(t *sc55.TextRegister) subcommands.ExitStatus {
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	data, err := queryBlock(in, out, deviceID(), t.Block(), c.timeout)
+	if err != nil {
+		log.Printf("error querying register %q: %v", t.Name(), err)
+		return subcommands.ExitFailure
+	}
+	value, err := t.Decode(data)
+	if err != nil {
+		log.Printf("error querying register %q: %v", t.Name(), err)
+		return subcommands.ExitFailure
+	}
+	fmt.Printf("%-30s  %q\n", t.Name(), value)
+	return subcommands.ExitSuccess
+}
+
 func (c *getRegisterCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
 	var registers []*sc55.Register
 	if len(f.Args()) > 0 {
 		regName := f.Args()[0]
+		if t, ok := sc55.TextRegisterByName(regName); ok {
+			return c.getText(t)
+		}
 		r, ok := sc55.RegisterByName(regName)
 		if !ok {
 			log.Printf("unknown register %q", regName)
@@ -162,9 +432,11 @@ func (c *getRegisterCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...in
 		}
 		registers = append(registers, r)
 	} else {
-		registers = sc55.AllRegisters()
-		if !c.all {
-			registers = onlyImportant(registers)
+		var err error
+		registers, err = selectRegisters(c.all, c.tags, c.groups)
+		if err != nil {
+			log.Printf("%v", err)
+			return subcommands.ExitUsageError
 		}
 	}
 	in, err := openInputStream()
@@ -178,14 +450,22 @@ func (c *getRegisterCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...in
 		return subcommands.ExitFailure
 	}
 	result := subcommands.ExitSuccess
+	var values map[*sc55.Register]int
+	var errs map[*sc55.Register]error
+	if c.bulk {
+		values, errs = queryRegisters(in, out, deviceID(), registers, c.timeout)
+	}
 	for _, r := range registers {
-		value, err := c.queryRegister(in, out, r)
+		value, err := values[r], errs[r]
+		if !c.bulk {
+			value, err = queryRegister(in, out, deviceID(), r, c.timeout)
+		}
 		if err != nil {
 			log.Printf("error querying register %q: %v", r.Name(), err)
 			result = subcommands.ExitFailure
 			continue
 		}
-		fmt.Printf("%-30s  %6d\n", r.Name(), value)
+		fmt.Printf("%-30s  %6s\n", r.Name(), r.FormatValue(value))
 	}
 	return result
 }
@@ -194,11 +474,27 @@ type cmd struct {
 	name, synopsis string
 	minArgs        int
 	produceData    func([]string) ([]byte, error)
+	flags          func(*flag.FlagSet)
+	// autoCheckpoint, if not nil, returns true if the write is risky
+	// enough that a checkpoint should be saved unless -checkpoint=false
+	// is given.
+	autoCheckpoint func() bool
+	checkpoint     bool
 }
 
-func (c *cmd) Name() string           { return c.name }
-func (c *cmd) Synopsis() string       { return c.synopsis }
-func (*cmd) SetFlags(f *flag.FlagSet) { setCommonFlags(f) }
+func (c *cmd) Name() string     { return c.name }
+func (c *cmd) Synopsis() string { return c.synopsis }
+func (c *cmd) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	if c.autoCheckpoint != nil {
+		f.BoolVar(&c.checkpoint, "checkpoint", false, "save the device state first, so that the change can be undone with rollback; on by default for writes that are hard to undo, such as -raw, unless -checkpoint=false is given")
+	} else {
+		setCheckpointFlag(f, &c.checkpoint, false)
+	}
+	if c.flags != nil {
+		c.flags(f)
+	}
+}
 func (c *cmd) Usage() string {
 	return fmt.Sprintf("%s [...]:\n%s\n", c.Name(), c.Synopsis())
 }
@@ -210,20 +506,44 @@ func (c *cmd) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subc
 	}
 	msg, err := c.produceData(f.Args())
 	if err != nil {
+		log.Printf("%s: %v", c.name, err)
 		return subcommands.ExitUsageError
 	}
+	checkpoint, auto := c.checkpoint, false
+	if c.autoCheckpoint != nil && !flagGiven(f, "checkpoint") && c.autoCheckpoint() {
+		checkpoint, auto = true, true
+	}
+	if checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			if auto {
+				log.Printf("failed to save checkpoint (use -checkpoint=false to go ahead without one): %v", err)
+			} else {
+				log.Printf("failed to save checkpoint: %v", err)
+			}
+			return subcommands.ExitFailure
+		}
+	}
 	out, err := openOutputStream()
 	if err != nil {
 		log.Printf("failed to open output stream: %v", err)
 		return subcommands.ExitFailure
 	}
-	if err := out.WriteSysExBytes(portmidi.Time(), msg); err != nil {
+	if err := writeSysEx(out, msg); err != nil {
 		log.Printf("failed to write message to output: %v", err)
 		return subcommands.ExitFailure
 	}
 	return subcommands.ExitSuccess
 }
 
+// flagGiven returns true if the named flag was set on the command line.
+func flagGiven(f *flag.FlagSet, name string) bool {
+	given := false
+	f.Visit(func(fl *flag.Flag) {
+		given = given || fl.Name == name
+	})
+	return given
+}
+
 func setParameterCallback(f func(sc55.DeviceID, int) []byte) func([]string) ([]byte, error) {
 	return func(args []string) ([]byte, error) {
 		val, err := strconv.ParseInt(args[0], 10, 64)
@@ -234,6 +554,25 @@ func setParameterCallback(f func(sc55.DeviceID, int) []byte) func([]string) ([]b
 	}
 }
 
+// parseBytes parses a list of MIDI data bytes given as decimal, or as hex
+// with an 0x prefix.
+func parseBytes(args []string) ([]byte, error) {
+	result := []byte{}
+	for _, arg := range args {
+		b, err := strconv.ParseUint(arg, 0, 8)
+		if err != nil {
+			return nil, err
+		}
+		if b > 0x7f {
+			return nil, fmt.Errorf("%s is not a valid MIDI data byte", arg)
+		}
+		result = append(result, byte(b))
+	}
+	return result, nil
+}
+
+var setRaw bool
+
 var commands = []subcommands.Command{
 	&cmd{
 		name:     "reset-gm",
@@ -243,10 +582,18 @@ var commands = []subcommands.Command{
 		},
 	},
 	&cmd{
-		name:     "reset-gs",
-		synopsis: "Reset the SoundCanvas into GS mode",
+		name:     "reset-gm2",
+		synopsis: "Send a General MIDI 2 System On message, for GM2 modules",
+		produceData: func([]string) ([]byte, error) {
+			return sc55.ResetGM2(deviceID()), nil
+		},
+	},
+	&resetGSCommand{},
+	&cmd{
+		name:     "reset-all",
+		synopsis: "Reinitialize everything, including the system mode (SC-88 and later)",
 		produceData: func([]string) ([]byte, error) {
-			return sc55.ResetGS(deviceID()), nil
+			return sc55.ResetAll(deviceID()), nil
 		},
 	},
 	&cmd{
@@ -258,46 +605,129 @@ var commands = []subcommands.Command{
 			return sc55.DisplayMessage(deviceID(), msg), nil
 		},
 	},
+	&displayImageCommand{},
 	&cmd{
-		name:     "display-image",
-		synopsis: "Show a picture on the SC-55 front panel",
+		name:     "display-text",
+		synopsis: "Show up to 3 characters (eg. \"OK\") in large letters on the SC-55 front panel picture display",
 		minArgs:  1,
 		produceData: func(args []string) ([]byte, error) {
-			in, err := os.Open(args[0])
-			if err != nil {
-				return nil, err
+			return sc55.DisplayText(deviceID(), strings.Join(args, " "))
+		},
+	},
+	&cmd{
+		name:        "scale-tuning",
+		synopsis:    "Set the scale tuning of a part, as a named temperament or 12 values in cents",
+		minArgs:     2,
+		produceData: scaleTuning,
+	},
+	&cmd{
+		name:     "voice-reserve",
+		synopsis: "Set the number of voices reserved for each of the 16 parts, in part order",
+		minArgs:  16,
+		produceData: func(args []string) ([]byte, error) {
+			var voices [16]int
+			if len(args) != len(voices) {
+				return nil, fmt.Errorf("want %d values, got %d", len(voices), len(args))
 			}
-			defer in.Close()
-			img, err := png.Decode(in)
-			if err != nil {
-				return nil, err
+			for i, arg := range args {
+				v, err := strconv.Atoi(arg)
+				if err != nil {
+					return nil, err
+				}
+				voices[i] = v
 			}
-			return sc55.DisplayImage(deviceID(), img)
+			return sc55.SetVoiceReserve(deviceID(), voices)
 		},
 	},
 	&listRegistersCommand{},
 	&getRegisterCommand{},
+	&followVolumeCommand{},
+	&mirrorCommand{},
+	&checkpointCommand{},
+	&rollbackCommand{},
+	&applyCommand{},
+	&provisionCommand{},
+	&decodeCommand{},
+	&exportSetupMIDICommand{},
+	&exportCCommand{},
+	&reportCommand{},
+	&normalizeCommand{},
+	&migrateCommand{},
+	&schemaExportCommand{},
+	&docsCommand{},
+	&proxyCommand{},
+	&transposeCommand{},
+	&detuneCommand{},
+	&spreadCommand{},
+	&keyRangeCommand{},
+	&partSetupCommand{},
+	&slewCommand{},
+	&normalizeLevelsCommand{},
+	&drumMapCommand{},
+	&setInstrumentCommand{},
+	&toneCommand{},
+	&displayScrollCommand{},
+	&bulkDumpCommand{},
+	&wizardCommand{},
+	&calibrateCommand{},
+	&identifyCommand{},
+	&setDeviceIDCommand{},
+	&buttonCommand{},
+	&recordMIDICommand{},
+	&versionCommand{},
+	&capabilitiesCommand{},
 	&cmd{
 		name:     "set",
 		synopsis: "set the value of a register",
 		minArgs:  2,
+		flags: func(f *flag.FlagSet) {
+			f.BoolVar(&setRaw, "raw", false, "write the given bytes exactly, with no zero offset or clamping")
+		},
+		// Raw bytes skip the range checks, so a mistake can leave
+		// the device in a state that's hard to work out by hand.
+		autoCheckpoint: func() bool { return setRaw },
 		produceData: func(args []string) ([]byte, error) {
+			if t, ok := sc55.TextRegisterByName(args[0]); ok {
+				return t.Set(deviceID(), strings.Join(args[1:], " "))
+			}
 			r, ok := sc55.RegisterByName(args[0])
 			if !ok {
 
 				return nil, fmt.Errorf("unknown register %q", args[0])
 			}
-			val, err := strconv.ParseInt(args[1], 10, 32)
+			if setRaw {
+				data, err := parseBytes(args[1:])
+				if err != nil {
+					return nil, err
+				}
+				return r.SetRaw(deviceID(), data...), nil
+			}
+			val, err := r.ParseValue(args[1])
 			if err != nil {
 				return nil, err
 			}
-			return r.Set(deviceID(), int(val)), nil
+			return setRegister(r, deviceID(), val)
 		},
 	},
 }
 
 func main() {
+	flag.BoolVar(&readOnly, "read_only", false, "refuse to send any message that changes the state of the device")
+	flag.BoolVar(&strict, "strict", false, "fail when a register value is out of range, instead of clamping it")
+	flag.BoolVar(&showStats, "stats", false, "log how many requests were made to the device and how quickly it replied")
+	modelName := flag.String("model", sc55.ModelSC55.String(), "SoundCanvas model being controlled, which determines the registers available: "+strings.Join(profileNames(), ", ")+"; if not given, the model saved by identify is used")
 	flag.Parse()
+	flag.Visit(func(f *flag.Flag) {
+		modelGiven = modelGiven || f.Name == "model"
+	})
+	profile, ok := sc55.ProfileByName(*modelName)
+	if !ok {
+		log.Fatalf("unknown model %q", *modelName)
+	}
+	sc55.SetProfile(profile)
+	if err := applyConfig(); err != nil {
+		log.Printf("failed to load config: %v", err)
+	}
 	if err := portmidi.Initialize(); err != nil {
 		log.Fatalf("failed to initialize portmidi: %v", err)
 	}
@@ -307,5 +737,9 @@ func main() {
 		subcommands.Register(cmd, "")
 	}
 	ctx := context.Background()
-	os.Exit(int(subcommands.Execute(ctx)))
+	status := subcommands.Execute(ctx)
+	if showStats {
+		logStats()
+	}
+	os.Exit(int(status))
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+)
+
+// temperaments are named scale tunings, as offsets in cents from equal
+// temperament for C through B, all based on C.
+var temperaments = map[string][12]int{
+	"equal":       {0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+	"just":        {0, 12, 4, 16, -14, -2, -10, 2, 14, -16, 18, -12},
+	"pythagorean": {0, -10, 4, -6, 8, -2, 12, 2, -8, 6, -4, 10},
+	// Quarter-comma meantone: a chain of fifths from Eb to G#, each
+	// narrowed by a quarter of the syntonic comma to 1200*log2(5)/4 =
+	// 696.58 cents, so that major thirds are pure; the values match
+	// the table in Wikipedia's "Quarter-comma meantone" article.
+	"meantone": {0, -24, -7, 10, -14, 3, -21, -3, -27, -10, 7, -17},
+}
+
+func temperamentNames() string {
+	names := []string{}
+	for name := range temperaments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// scaleTuning is the produceData callback for the scale-tuning command. The
+// arguments are a part number and either the name of a temperament or
+// twelve values in cents.
+func scaleTuning(args []string) ([]byte, error) {
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return nil, err
+	}
+	p := sc55.PartByNumber(n)
+	if p == nil {
+		return nil, fmt.Errorf("invalid part number %d", n)
+	}
+	var cents [12]int
+	switch len(args) {
+	case 2:
+		var ok bool
+		cents, ok = temperaments[args[1]]
+		if !ok {
+			return nil, fmt.Errorf("unknown temperament %q: valid temperaments: %s", args[1], temperamentNames())
+		}
+	case 13:
+		for i, arg := range args[1:] {
+			cents[i], err = strconv.Atoi(arg)
+			if err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("want a temperament name or 12 values in cents, got %d values", len(args)-1)
+	}
+	return p.SetScaleTuning(deviceID(), cents), nil
+}
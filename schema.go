@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type jsonObject = map[string]interface{}
+
+// settingsSchema returns a JSON Schema describing the settings file format.
+func settingsSchema() jsonObject {
+	registers := jsonObject{}
+	for _, r := range sc55.AllRegisters() {
+		min, max := r.Range()
+		description := fmt.Sprintf("Register at address 0x%06x", r.Address)
+		if names := r.ValueNames(); names != nil {
+			values := []string{}
+			for i, name := range names {
+				values = append(values, fmt.Sprintf("%d=%s", i, name))
+			}
+			description += "; values: " + strings.Join(values, ", ")
+		}
+		registers[r.Name()] = jsonObject{
+			"type":        "integer",
+			"minimum":     min,
+			"maximum":     max,
+			"description": description,
+		}
+	}
+	text := jsonObject{}
+	for _, t := range sc55.AllTextRegisters() {
+		text[t.Name()] = jsonObject{
+			"type":        "string",
+			"maxLength":   t.Size,
+			"description": fmt.Sprintf("Text register at address 0x%06x", t.Address),
+		}
+	}
+	return jsonObject{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"title":       "sc55ctl settings",
+		"description": "Register values for a Roland SoundCanvas, as used by sc55ctl apply and checkpoint",
+		"type":        "object",
+		"properties": jsonObject{
+			"version": jsonObject{
+				"type":        "integer",
+				"maximum":     settingsVersion,
+				"description": "Version of the file format; older files can be upgraded with sc55ctl migrate",
+			},
+			"registers": jsonObject{
+				"type":                 "object",
+				"properties":           registers,
+				"additionalProperties": false,
+			},
+			"text": jsonObject{
+				"type":                 "object",
+				"properties":           text,
+				"additionalProperties": false,
+			},
+			"skipped": jsonObject{
+				"type":        "array",
+				"description": "Blocks of memory the device did not implement when the file was saved",
+				"items": jsonObject{
+					"type": "object",
+					"properties": jsonObject{
+						"address": jsonObject{"type": "string", "pattern": "^[0-9a-f]{6}$"},
+						"size":    jsonObject{"type": "integer", "minimum": 1},
+					},
+					"required": []string{"address", "size"},
+				},
+			},
+		},
+		"required": []string{"registers"},
+	}
+}
+
+type schemaExportCommand struct{}
+
+func (*schemaExportCommand) Name() string { return "schema-export" }
+func (*schemaExportCommand) Synopsis() string {
+	return "print a JSON Schema for settings files, for editor validation and completion"
+}
+func (*schemaExportCommand) Usage() string { return "" }
+
+func (*schemaExportCommand) SetFlags(*flag.FlagSet) {}
+
+func (*schemaExportCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	data, err := json.MarshalIndent(settingsSchema(), "", "\t")
+	if err != nil {
+		log.Printf("failed to generate schema: %v", err)
+		return subcommands.ExitFailure
+	}
+	os.Stdout.Write(append(data, '\n'))
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type setDeviceIDCommand struct{}
+
+func (*setDeviceIDCommand) Name() string { return "set-device-id" }
+func (*setDeviceIDCommand) Synopsis() string {
+	return "change the device ID by SysEx (needs a custom_registers entry in the config file)"
+}
+func (*setDeviceIDCommand) Usage() string {
+	return `set-device-id <id>:
+Changes the device ID of the device selected with -sc55_device_id to the
+given ID (decimal, or hex with an 0x prefix), so that several modules can
+share a bus. Later commands must then be given the new -sc55_device_id.
+
+None of the built-in profiles has a register for this, because those
+products set their device ID from the front panel. The command only
+works once a register named "` + sc55.DeviceIDRegisterName + `" has been added to the custom_registers
+list in the config file, for firmware that can change its ID by SysEx,
+eg:
+
+  "custom_registers": [
+    {"name": "` + sc55.DeviceIDRegisterName + `", "address": "...", "size": 1, "min": 0, "max": 31}
+  ]
+`
+}
+
+func (*setDeviceIDCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+}
+
+func (c *setDeviceIDCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	id, err := strconv.ParseInt(f.Args()[0], 0, 32)
+	if err != nil {
+		log.Printf("invalid device ID %q: %v", f.Args()[0], err)
+		return subcommands.ExitUsageError
+	}
+	r, err := sc55.CurrentProfile().DeviceIDRegister()
+	if err != nil {
+		log.Printf("%v; add a custom_registers entry named %q to %s to use this command", err, sc55.DeviceIDRegisterName, configFilename())
+		return subcommands.ExitFailure
+	}
+	if deviceID() == sc55.BroadcastDevice {
+		log.Printf("-sc55_device_id must name a single device, not the broadcast ID %d", sc55.BroadcastDevice)
+		return subcommands.ExitUsageError
+	}
+	min, max := r.Range()
+	if int(id) < min || int(id) > max || sc55.DeviceID(id) == sc55.BroadcastDevice {
+		log.Printf("invalid device ID %d, want %d <= x <= %d", id, min, max)
+		return subcommands.ExitUsageError
+	}
+	msg, err := r.Set(deviceID(), int(id))
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitFailure
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	if err := writeSysEx(out, msg); err != nil {
+		log.Printf("failed to write message to output: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("device ID changed from %d to %d; use -sc55_device_id=%d from now on", deviceID(), id, id)
+	return subcommands.ExitSuccess
+}
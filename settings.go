@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/rakyll/portmidi"
+)
+
+// bulkWriteDelay is the pause between messages when writing many registers,
+// so that the SoundCanvas's receive buffer isn't overrun. It can be tuned
+// for the MIDI interface in use with calibrate.
+var bulkWriteDelay = 20 * time.Millisecond
+
+// replyTimeout is how long bulk operations wait for a reply from the
+// SoundCanvas before giving up.
+const replyTimeout = 100 * time.Millisecond
+
+// settings is a set of register values keyed by register name, as saved to
+// and loaded from disk.
+type settings map[string]int
+
+// settingsFile is the format of a file containing settings.
+type settingsFile struct {
+	// Version is the version of the file format (see settingsVersion).
+	// Files are upgraded to the current version when they are loaded.
+	Version   int      `json:"version"`
+	Registers settings `json:"registers"`
+	// Skipped lists the blocks of memory that weren't saved because the
+	// device doesn't implement them.
+	Skipped []skippedBlock `json:"skipped,omitempty"`
+	// Text holds the values of text registers such as the patch name.
+	Text map[string]string `json:"text,omitempty"`
+
+	// loadedVersion is the version that the file had when it was loaded.
+	loadedVersion int
+}
+
+type skippedBlock struct {
+	Address string `json:"address"`
+	Size    int    `json:"size"`
+}
+
+func loadSettingsFile(filename string) (*settingsFile, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	f := &settingsFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+	}
+	switch {
+	case f.Registers == nil:
+		// Version 0 files were just a map of register values.
+		if err := json.Unmarshal(data, &f.Registers); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", filename, err)
+		}
+	case f.Version == 0:
+		// Version 1 files had no version field.
+		f.Version = 1
+	}
+	f.loadedVersion = f.Version
+	if err := f.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to load %s: %v", filename, err)
+	}
+	return f, nil
+}
+
+func loadSettings(filename string) (settings, error) {
+	f, err := loadSettingsFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return f.Registers, nil
+}
+
+// MarshalJSON encodes the settings with registers in address order (rather
+// than the alphabetical order encoding/json uses for maps) so that files
+// are laid out the same way as the device's memory and diff cleanly.
+func (s settings) MarshalJSON() ([]byte, error) {
+	regs, unknown := s.registers()
+	names := []string{}
+	for _, r := range regs {
+		names = append(names, r.Name())
+	}
+	names = append(names, unknown...)
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		fmt.Fprintf(&buf, ":%d", s[name])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (f *settingsFile) save(filename string) error {
+	f.Version = settingsVersion
+	data, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// registers returns the registers named in the settings, sorted by address,
+// along with the names of any registers that don't exist.
+func (s settings) registers() ([]*sc55.Register, []string) {
+	result := []*sc55.Register{}
+	for _, r := range sc55.AllRegisters() {
+		if _, ok := s[r.Name()]; ok {
+			result = append(result, r)
+		}
+	}
+	unknown := []string{}
+	for name := range s {
+		if _, ok := sc55.RegisterByName(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return result, unknown
+}
+
+// fetchSettings reads the current values of the given registers from the
+// device. Before reading each block of memory, a sentinel register is probed;
+// blocks that the device doesn't respond to (eg. extensions that only exist
+// on later models) are skipped and returned separately rather than causing
+// the whole operation to fail.
+func fetchSettings(in, out *portmidi.Stream, regs []*sc55.Register, timeout time.Duration) (settings, []sc55.Block, error) {
+	implemented := []*sc55.Register{}
+	skipped := []sc55.Block{}
+	for _, b := range sc55.Blocks(regs) {
+		var blockRegs []*sc55.Register
+		for _, r := range regs {
+			if b.Contains(r) {
+				blockRegs = append(blockRegs, r)
+			}
+		}
+		if _, err := queryRegister(in, out, deviceID(), blockRegs[0], timeout); err != nil {
+			log.Printf("skipping block %x-%x: %v", b.Address, b.Address+b.Size-1, err)
+			skipped = append(skipped, b)
+			continue
+		}
+		implemented = append(implemented, blockRegs...)
+	}
+	values, errs := queryRegisters(in, out, deviceID(), implemented, timeout)
+	s := settings{}
+	for _, r := range implemented {
+		if err := errs[r]; err != nil {
+			return nil, nil, fmt.Errorf("error querying register %q: %v", r.Name(), err)
+		}
+		s[r.Name()] = values[r]
+	}
+	return s, skipped, nil
+}
+
+// fetchText reads the values of all text registers from the device, leaving
+// out any that the device doesn't respond to.
+func fetchText(in, out *portmidi.Stream, timeout time.Duration) map[string]string {
+	result := map[string]string{}
+	for _, t := range sc55.AllTextRegisters() {
+		data, err := queryBlock(in, out, deviceID(), t.Block(), timeout)
+		if err != nil {
+			continue
+		}
+		if value, err := t.Decode(data); err == nil {
+			result[t.Name()] = value
+		}
+	}
+	return result
+}
+
+// registerFailure records a register that could not be written.
+type registerFailure struct {
+	Register string `json:"register"`
+	Error    string `json:"error"`
+}
+
+// failureReport lists the registers that failed during a bulk operation.
+type failureReport []registerFailure
+
+func (f *failureReport) add(name string, err error) {
+	*f = append(*f, registerFailure{name, err.Error()})
+}
+
+// err summarizes the report as a single error, or nil if nothing failed.
+func (f failureReport) err() error {
+	if len(f) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d registers failed, first: %s: %s", len(f), f[0].Register, f[0].Error)
+}
+
+func (f failureReport) log() {
+	for _, failure := range f {
+		log.Printf("%-30s  %s", failure.Register, failure.Error)
+	}
+}
+
+func (f failureReport) save(filename string) error {
+	data, err := json.MarshalIndent(f, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+// apply writes all the values in the settings to the given device. A failure
+// doesn't stop the remaining registers from being written; all failures are
+// collected and returned together. If in is not nil, each register is read
+// back afterwards to check that the device accepted the value; otherwise
+// registers at contiguous addresses are written together in one message.
+func (s settings) apply(in, out *portmidi.Stream, device sc55.DeviceID) failureReport {
+	regs, unknown := s.registers()
+	failures := failureReport{}
+	for _, name := range unknown {
+		failures.add(name, fmt.Errorf("unknown register"))
+	}
+	if in == nil {
+		return append(failures, s.applyCoalesced(out, device, regs)...)
+	}
+	for _, r := range regs {
+		value := s[r.Name()]
+		msg, err := setRegister(r, device, value)
+		if err == nil {
+			err = writeSysEx(out, msg)
+		}
+		if err != nil {
+			failures.add(r.Name(), err)
+			continue
+		}
+		time.Sleep(bulkWriteDelay)
+		min, max := r.Range()
+		want := sc55.Clamp(value, min, max)
+		got, err := queryRegister(in, out, device, r, replyTimeout)
+		switch {
+		case err != nil:
+			failures.add(r.Name(), fmt.Errorf("failed to verify: %v", err))
+		case got != want:
+			failures.add(r.Name(), fmt.Errorf("write rejected: wrote %d, read back %d", want, got))
+		}
+	}
+	return failures
+}
+
+// applyText writes the values of text registers to the given device.
+func applyText(out *portmidi.Stream, device sc55.DeviceID, text map[string]string) failureReport {
+	failures := failureReport{}
+	names := []string{}
+	for name := range text {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t, ok := sc55.TextRegisterByName(name)
+		if !ok {
+			failures.add(name, fmt.Errorf("unknown text register"))
+			continue
+		}
+		msg, err := t.Set(device, text[name])
+		if err != nil {
+			failures.add(name, err)
+			continue
+		}
+		if err := writeSysEx(out, msg); err != nil {
+			failures.add(name, err)
+			continue
+		}
+		time.Sleep(bulkWriteDelay)
+	}
+	return failures
+}
+
+// applyCoalesced writes the given registers using as few messages as
+// possible. If a message can't be sent, every register in it is reported
+// as failed.
+func (s settings) applyCoalesced(out *portmidi.Stream, device sc55.DeviceID, regs []*sc55.Register) failureReport {
+	failures := failureReport{}
+	values := map[*sc55.Register]int{}
+	for _, r := range regs {
+		// Registers that can't be encoded are left out and reported
+		// individually, rather than failing the whole lot.
+		if _, err := setRegister(r, device, s[r.Name()]); err != nil {
+			failures.add(r.Name(), err)
+			continue
+		}
+		values[r] = s[r.Name()]
+	}
+	msgs, err := sc55.SetMany(device, values)
+	if err != nil {
+		failures.add("", err)
+		return failures
+	}
+	for _, msg := range msgs {
+		err := writeSysEx(out, msg)
+		time.Sleep(bulkWriteDelay)
+		if err == nil {
+			continue
+		}
+		_, addr, data, _ := sc55.UnmarshalSet(msg)
+		for _, r := range regs {
+			if _, ok := values[r]; ok && r.Address >= addr && r.Address < addr+len(data) {
+				failures.add(r.Name(), err)
+			}
+		}
+	}
+	return failures
+}
+
+// currentSettings returns the settings from the given file or, if filename
+// is empty, reads them from the device.
+func currentSettings(filename string, all bool) (settings, error) {
+	if filename != "" {
+		return loadSettings(filename)
+	}
+	in, err := openInputStream()
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	regs := sc55.AllRegisters()
+	if !all {
+		regs = onlyImportant(regs)
+	}
+	s, _, err := fetchSettings(in, out, regs, replyTimeout)
+	return s, err
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+// partForRegister returns the part that the given register belongs to, or
+// nil if it isn't a part register.
+func partForRegister(r *sc55.Register) *sc55.Part {
+	for i := 1; i <= sc55.PartCount(); i++ {
+		p := sc55.PartByNumber(i)
+		if r.Address&^0xff == p.ToneNumber.Address&^0xff {
+			return p
+		}
+	}
+	return nil
+}
+
+type slewCommand struct {
+	duration time.Duration
+	interval time.Duration
+}
+
+func (*slewCommand) Name() string { return "slew" }
+func (*slewCommand) Synopsis() string {
+	return "change a part level, pan or effect send smoothly using control changes"
+}
+func (*slewCommand) Usage() string {
+	return `slew [-time duration] [-interval duration] <register> <value>:
+Moves a part register with a MIDI controller equivalent (part-level,
+pan-pot, reverb-send-level or chorus-send-level) from its current value to
+the given value over -time, by sending control changes on the part's
+receive channel. Setting the register with SysEx makes the value jump,
+which can be heard on notes that are playing; this is meant for changes
+during a performance. The part must receive control changes.
+`
+}
+
+func (c *slewCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.DurationVar(&c.duration, "time", 500*time.Millisecond, "how long to take to reach the new value")
+	f.DurationVar(&c.interval, "interval", 10*time.Millisecond, "time between control changes")
+}
+
+func (c *slewCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 2 || c.interval <= 0 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	r, ok := sc55.RegisterByName(f.Args()[0])
+	if !ok {
+		log.Printf("unknown register %q", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	p := partForRegister(r)
+	if _, ok := r.Controller(); !ok || p == nil {
+		log.Printf("register %q has no controller equivalent; use set instead", r.Name())
+		return subcommands.ExitUsageError
+	}
+	target, err := r.ParseValue(f.Args()[1])
+	if err == nil && strict {
+		err = r.CheckRange(target)
+	}
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	current, _, err := fetchSettings(in, out, []*sc55.Register{r, &p.RxChannel, &p.RxControlChange}, replyTimeout)
+	if err != nil {
+		log.Printf("failed to read part: %v", err)
+		return subcommands.ExitFailure
+	}
+	channel := current[p.RxChannel.Name()] + 1
+	switch {
+	case channel > 16:
+		log.Printf("part doesn't receive on any channel; use set instead")
+		return subcommands.ExitFailure
+	case current[p.RxControlChange.Name()] == 0:
+		log.Printf("part doesn't receive control changes; use set instead")
+		return subcommands.ExitFailure
+	}
+	for i, v := range sc55.Ramp(current[r.Name()], target, int(c.duration/c.interval)) {
+		if i > 0 {
+			time.Sleep(c.interval)
+		}
+		msg, err := r.ControlChange(channel, v)
+		if err != nil {
+			log.Printf("%v", err)
+			return subcommands.ExitFailure
+		}
+		e := portmidi.Event{Status: int64(msg[0]), Data1: int64(msg[1]), Data2: int64(msg[2])}
+		if err := writeEvent(out, e); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
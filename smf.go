@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"time"
+)
+
+const (
+	// smfDivision is the number of ticks per quarter note in files we
+	// write. With the default tempo of 120bpm, one tick is just over a
+	// millisecond.
+	smfDivision = 480
+	smfTempo    = 500000 // microseconds per quarter note
+)
+
+// smfTrack accumulates the events of a Standard MIDI File track.
+type smfTrack struct {
+	data     []byte
+	lastTick int
+}
+
+// smfTicks converts a duration to a number of ticks at the default tempo.
+func smfTicks(d time.Duration) int {
+	return int(d.Microseconds() * smfDivision / smfTempo)
+}
+
+func appendVarLen(data []byte, value int) []byte {
+	buf := []byte{byte(value & 0x7f)}
+	for value >>= 7; value > 0; value >>= 7 {
+		buf = append([]byte{byte(value&0x7f) | 0x80}, buf...)
+	}
+	return append(data, buf...)
+}
+
+func (t *smfTrack) delta(tick int) {
+	if tick < t.lastTick {
+		tick = t.lastTick
+	}
+	t.data = appendVarLen(t.data, tick-t.lastTick)
+	t.lastTick = tick
+}
+
+// addEvent adds a channel message at the given tick.
+func (t *smfTrack) addEvent(tick int, msg ...byte) {
+	t.delta(tick)
+	t.data = append(t.data, msg...)
+}
+
+// addSysEx adds a complete SysEx message (including the leading F0 and
+// trailing F7) at the given tick.
+func (t *smfTrack) addSysEx(tick int, msg []byte) {
+	t.delta(tick)
+	t.data = append(t.data, 0xf0)
+	t.data = appendVarLen(t.data, len(msg)-1)
+	t.data = append(t.data, msg[1:]...)
+}
+
+func (t *smfTrack) addMeta(tick int, metaType byte, data ...byte) {
+	t.delta(tick)
+	t.data = append(t.data, 0xff, metaType)
+	t.data = appendVarLen(t.data, len(data))
+	t.data = append(t.data, data...)
+}
+
+func (t *smfTrack) addTempo(tick, microsecondsPerQuarter int) {
+	us := microsecondsPerQuarter
+	t.addMeta(tick, 0x51, byte(us>>16), byte(us>>8), byte(us))
+}
+
+// writeSMF writes a type 0 Standard MIDI File containing the given track.
+func writeSMF(filename string, t *smfTrack) error {
+	var buf bytes.Buffer
+	buf.WriteString("MThd")
+	binary.Write(&buf, binary.BigEndian, []uint32{6})
+	binary.Write(&buf, binary.BigEndian, []uint16{0, 1, smfDivision})
+	data := append([]byte{}, t.data...)
+	// End of track:
+	data = append(data, 0x00, 0xff, 0x2f, 0x00)
+	buf.WriteString("MTrk")
+	binary.Write(&buf, binary.BigEndian, uint32(len(data)))
+	buf.Write(data)
+	return os.WriteFile(filename, buf.Bytes(), 0644)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// activeParts returns the numbers of the parts that are receiving on a MIDI
+// channel and playing normal (non-rhythm) tones.
+func activeParts(s settings) []int {
+	result := []int{}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		p := sc55.PartByNumber(i)
+		if s[p.RxChannel.Name()] < 0x10 && s[p.UseForRhythm.Name()] == 0 {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// panSpread returns pan-pot settings that spread the given parts evenly
+// from amount left to amount right, in part order.
+func panSpread(parts []int, amount int) settings {
+	result := settings{}
+	for i, part := range parts {
+		pan := 0
+		if len(parts) > 1 {
+			pan = -amount + 2*amount*i/(len(parts)-1)
+		}
+		result[sc55.PartByNumber(part).PanPot.Name()] = pan
+	}
+	return result
+}
+
+type spreadCommand struct {
+	checkpoint bool
+}
+
+func (*spreadCommand) Name() string { return "spread" }
+func (*spreadCommand) Synopsis() string {
+	return "spread the pan of the active parts across the stereo field"
+}
+func (*spreadCommand) Usage() string {
+	return `spread <amount>:
+Pans the active parts evenly between -amount and +amount (up to 63). Rhythm
+parts and parts with no receive channel are left alone; an amount of 0
+returns the active parts to the center.
+`
+}
+
+func (c *spreadCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setCheckpointFlag(f, &c.checkpoint, true)
+}
+
+func (c *spreadCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	amount, err := strconv.Atoi(f.Args()[0])
+	if err != nil || amount < 0 || amount > 63 {
+		log.Printf("invalid amount %q: must be 0-63", f.Args()[0])
+		return subcommands.ExitUsageError
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint (use -checkpoint=false to go ahead without one): %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	in, err := openInputStream()
+	if err != nil {
+		log.Printf("failed to open input stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer in.Close()
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	regs := []*sc55.Register{}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		p := sc55.PartByNumber(i)
+		regs = append(regs, &p.RxChannel, &p.UseForRhythm, &p.PanPot)
+	}
+	current, _, err := fetchSettings(in, out, regs, replyTimeout)
+	if err != nil {
+		log.Printf("failed to read parts: %v", err)
+		return subcommands.ExitFailure
+	}
+	// Only write the parts whose pan actually changes.
+	changed := changedSettings(current, panSpread(activeParts(current), amount))
+	if failures := changed.apply(nil, out, deviceID()); len(failures) > 0 {
+		failures.log()
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,3 @@
+module github.com/rakyll/portmidi
+
+go 1.14
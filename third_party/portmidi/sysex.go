@@ -0,0 +1,155 @@
+// Copyright 2013 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package portmidi
+
+// #include <portmidi.h>
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// sysexReader reassembles SysEx messages that span more than one call to
+// Pm_Read. Read can't do this: it only looks for the end of a message
+// among the events of a single read, and its buffer holds only
+// maxEventBufferSize bytes.
+type sysexReader struct {
+	mu sync.Mutex
+	// buf is the message being received, or nil if there is none.
+	buf []byte
+	// receiving is set between the start and end of a message.
+	receiving bool
+	// dropped is set once the message being received has grown past the
+	// size limit; the rest of it is then skipped.
+	dropped bool
+	// pending holds complete messages not yet returned by ReadSysEx.
+	pending [][]byte
+}
+
+// ReadEvents reads up to max events from the input stream without
+// blocking. Unlike Read, a SysEx message of any length is returned whole
+// as a single event once its final byte arrives, however many calls that
+// takes. A message longer than maxSysEx bytes is dropped and
+// ErrSysExOverflow returned along with the other events that were read;
+// maxSysEx of 0 means there is no limit.
+func (s *Stream) ReadEvents(max, maxSysEx int) ([]Event, error) {
+	s.sysex.mu.Lock()
+	defer s.sysex.mu.Unlock()
+	return s.readEvents(max, maxSysEx)
+}
+
+// ReadSysEx returns the next SysEx message received on the input stream,
+// or nil if there is none, without blocking. Other events are discarded.
+// Messages longer than maxSysEx bytes are dropped and reported as for
+// ReadEvents.
+func (s *Stream) ReadSysEx(maxSysEx int) ([]byte, error) {
+	s.sysex.mu.Lock()
+	defer s.sysex.mu.Unlock()
+	if msg := s.sysex.next(); msg != nil {
+		return msg, nil
+	}
+	if ok, err := s.Poll(); err != nil || !ok {
+		return nil, err
+	}
+	events, err := s.readEvents(maxEventBufferSize, maxSysEx)
+	for _, e := range events {
+		if e.SysEx != nil {
+			s.sysex.pending = append(s.sysex.pending, e.SysEx)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.sysex.next(), nil
+}
+
+// next removes and returns the oldest pending message, or nil.
+func (r *sysexReader) next() []byte {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	msg := r.pending[0]
+	r.pending[0] = nil
+	r.pending = r.pending[1:]
+	if len(r.pending) == 0 {
+		r.pending = nil
+	}
+	return msg
+}
+
+// readEvents implements ReadEvents; the caller holds s.sysex.mu.
+func (s *Stream) readEvents(max, maxSysEx int) ([]Event, error) {
+	if max > maxEventBufferSize {
+		return nil, ErrMaxBuffer
+	}
+	if max < minEventBufferSize {
+		return nil, ErrMinBuffer
+	}
+	buffer := make([]C.PmEvent, max)
+	numEvents := int(C.Pm_Read(unsafe.Pointer(s.pmStream), &buffer[0], C.int32_t(max)))
+	if numEvents < 0 {
+		return nil, convertToError(C.PmError(numEvents))
+	}
+	r := &s.sysex
+	var events []Event
+	var err error
+	for _, raw := range buffer[:numEvents] {
+		msg := uint32(raw.message)
+		event := Event{
+			Timestamp: Timestamp(raw.timestamp),
+			Status:    int64(msg & 0xFF),
+			Data1:     int64((msg >> 8) & 0xFF),
+			Data2:     int64((msg >> 16) & 0xFF),
+		}
+		switch {
+		case event.Status >= 0xF8:
+			// Real-time messages may arrive in the middle of a
+			// SysEx message.
+			events = append(events, event)
+			continue
+		case event.Status == 0xF0:
+			// A new message; any unfinished one was abandoned.
+			r.buf, r.receiving, r.dropped = nil, true, false
+		case !r.receiving:
+			events = append(events, event)
+			continue
+		case event.Status&0x80 != 0 && event.Status != 0xF7:
+			// A status byte other than EOX ends the message early.
+			r.buf, r.receiving, r.dropped = nil, false, false
+			events = append(events, event)
+			continue
+		}
+		for i := 0; i < 4; i++ {
+			b := byte(msg >> (8 * i))
+			if !r.dropped {
+				r.buf = append(r.buf, b)
+			}
+			if b == 0xF7 {
+				if !r.dropped {
+					event.SysEx, event.Data1, event.Data2 = r.buf, 0, 0
+					events = append(events, event)
+				}
+				r.buf, r.receiving, r.dropped = nil, false, false
+				break
+			}
+			if !r.dropped && maxSysEx > 0 && len(r.buf) >= maxSysEx {
+				r.buf, r.dropped = nil, true
+				err = ErrSysExOverflow
+			}
+		}
+	}
+	return events, err
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/fragglet/sc55ctl/sc55/notes"
+	"github.com/google/subcommands"
+	"github.com/rakyll/portmidi"
+)
+
+// pitchBendCenter is the 14-bit pitch bend value for no bend.
+const pitchBendCenter = 0x2000
+
+// parsePitch parses a frequency in Hz or a note name, returning the
+// frequency, or zero and the note number for a note.
+func parsePitch(s string) (float64, int, error) {
+	if hz, err := strconv.ParseFloat(s, 64); err == nil {
+		if hz <= 0 {
+			return 0, 0, fmt.Errorf("invalid frequency %q", s)
+		}
+		return hz, 0, nil
+	}
+	n, err := notes.NameToNumber(s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid pitch %q: want a frequency in Hz or a note name", s)
+	}
+	return 0, n, nil
+}
+
+// pitchBendValue returns the 14-bit pitch bend value that bends a note by
+// the given number of cents, when the bend range is the given number of
+// semitones.
+func pitchBendValue(cents float64, bendRange int) (int, error) {
+	v := pitchBendCenter + int(math.Round(cents/float64(bendRange*100)*pitchBendCenter))
+	if v < 0 || v > 0x3fff {
+		return 0, fmt.Errorf("can't bend by %.1f cents with a bend range of %d semitones", cents, bendRange)
+	}
+	return v, nil
+}
+
+type toneCommand struct {
+	duration   time.Duration
+	channel    int
+	velocity   int
+	bendRange  int
+	verify     bool
+	masterTune int
+}
+
+func (*toneCommand) Name() string { return "tone" }
+func (*toneCommand) Synopsis() string {
+	return "play a sine wave test tone at a precise frequency or note"
+}
+func (*toneCommand) Usage() string {
+	return `tone [flags] <frequency | note>:
+Plays the GS "Sine Wave" tone at the given frequency in Hz (eg. 1000) or
+note (eg. A4) on a channel, for calibrating recordings or checking the
+tuning of the device. Notes are played at the device's tuning; frequencies
+are reached with pitch bend from the nearest note, allowing for the master
+tune so that the exact frequency is heard. The channel's bend range must
+match -bend_range (2 semitones after a reset). The master tune is taken
+from -master_tune, or with -verify it is read from the device, and the
+frequency that should be heard is shown.
+`
+}
+
+func (c *toneCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.DurationVar(&c.duration, "duration", time.Second, "how long to play the tone for")
+	f.IntVar(&c.channel, "channel", 1, "MIDI channel (1-16) to play the tone on; its instrument is changed")
+	f.IntVar(&c.velocity, "velocity", 100, "note velocity")
+	f.IntVar(&c.bendRange, "bend_range", 2, "pitch bend range of the channel, in semitones")
+	f.BoolVar(&c.verify, "verify", false, "read master-tune from the device and show the frequencies involved")
+	f.IntVar(&c.masterTune, "master_tune", 0, "value of master-tune to assume when not using -verify")
+}
+
+// readMasterTune reads the master-tune register from the device.
+func readMasterTune(out *portmidi.Stream) (int, error) {
+	in, err := openInputStream()
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	return queryRegister(in, out, deviceID(), &sc55.MasterTune, replyTimeout)
+}
+
+// channelMessages returns the messages that play the tone: instrument
+// selection, pitch bend and the note, as events to send in order.
+func (c *toneCommand) channelMessages(note, bend int) ([]portmidi.Event, error) {
+	t, ok := sc55.ToneByName("Sine Wave")
+	if !ok {
+		return nil, fmt.Errorf("sine wave tone not found")
+	}
+	msgs, err := sc55.SelectTone(c.channel, t)
+	if err != nil {
+		return nil, err
+	}
+	ch := int64(c.channel - 1)
+	events := []portmidi.Event{}
+	for _, msg := range msgs {
+		e := portmidi.Event{Status: int64(msg[0]), Data1: int64(msg[1])}
+		if len(msg) > 2 {
+			e.Data2 = int64(msg[2])
+		}
+		events = append(events, e)
+	}
+	events = append(events,
+		portmidi.Event{Status: statusPitchBend | ch, Data1: int64(bend & 0x7f), Data2: int64(bend >> 7)},
+		portmidi.Event{Status: statusNoteOn | ch, Data1: int64(note), Data2: int64(c.velocity)},
+	)
+	return events, nil
+}
+
+func (c *toneCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 || c.channel < 1 || c.channel > 16 || c.velocity < 1 || c.velocity > 0x7f || c.bendRange < 1 {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	hz, note, err := parsePitch(f.Args()[0])
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	masterTune := c.masterTune
+	if c.verify {
+		masterTune, err = readMasterTune(out)
+		if err != nil {
+			log.Printf("failed to read master-tune: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	cents := 0.0
+	if hz == 0 {
+		hz = notes.TunedFrequency(note, masterTune)
+	} else {
+		note, cents = notes.FromFrequency(hz, masterTune)
+	}
+	if note < 0 || note > notes.Max {
+		log.Printf("%.2f Hz is outside the range of MIDI notes", hz)
+		return subcommands.ExitUsageError
+	}
+	bend, err := pitchBendValue(cents, c.bendRange)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if c.verify {
+		// Work back from what is actually sent, so that rounding of
+		// the bend is included.
+		sentCents := float64(bend-pitchBendCenter) / pitchBendCenter * float64(c.bendRange*100)
+		expected := notes.TunedFrequency(note, masterTune) * math.Pow(2, sentCents/1200)
+		fmt.Printf("master-tune = %d (A4 = %s)\n", masterTune, sc55.MasterTune.FormatValue(masterTune))
+		fmt.Printf("playing %s %+.2f cents: expect %.3f Hz (requested %.3f Hz, %+.3f cents)\n",
+			notes.NumberToName(note), sentCents, expected, hz, 1200*math.Log2(expected/hz))
+	}
+	events, err := c.channelMessages(note, bend)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitFailure
+	}
+	for _, e := range events {
+		if err := writeEvent(out, e); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	time.Sleep(c.duration)
+	ch := int64(c.channel - 1)
+	for _, e := range []portmidi.Event{
+		{Status: statusNoteOn | ch, Data1: int64(note), Data2: 0},
+		{Status: statusPitchBend | ch, Data1: pitchBendCenter & 0x7f, Data2: pitchBendCenter >> 7},
+	} {
+		if err := writeEvent(out, e); err != nil {
+			log.Printf("failed to write message to output: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	return subcommands.ExitSuccess
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+type transposeCommand struct {
+	part        int
+	master      bool
+	live        bool
+	inputDevice string
+	checkpoint  bool
+}
+
+func (*transposeCommand) Name() string { return "transpose" }
+func (*transposeCommand) Synopsis() string {
+	return "transpose the whole device, a single part, or live notes passing through a proxy"
+}
+func (*transposeCommand) Usage() string {
+	return `transpose [-master | -part N] [-live -input_midi_device name] <semitones>:
+Transpose by the given number of semitones. By default master-key-shift is
+set; with -part, that part's pitch-key-shift is set instead. With -live,
+no registers are changed: events from the input device are forwarded with
+their notes rewritten, for just the part's channel if -part is given.
+`
+}
+
+func (c *transposeCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setDisplayErrorFlags(f)
+	f.IntVar(&c.part, "part", 0, "transpose only the given part (1-16)")
+	f.BoolVar(&c.master, "master", false, "transpose the whole device (the default)")
+	f.BoolVar(&c.live, "live", false, "rewrite notes from -input_midi_device instead of changing registers")
+	f.StringVar(&c.inputDevice, "input_midi_device", "", "Name of the MIDI device to read events from in -live mode")
+	setCheckpointFlag(f, &c.checkpoint, false)
+}
+
+// register returns the key shift register to change.
+func (c *transposeCommand) register() (*sc55.Register, error) {
+	if c.part == 0 {
+		return &sc55.MasterKeyShift, nil
+	}
+	p := sc55.PartByNumber(c.part)
+	if p == nil {
+		return nil, fmt.Errorf("invalid part number %d", c.part)
+	}
+	return &p.PitchKeyShift, nil
+}
+
+func (c *transposeCommand) Execute(_ context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if len(f.Args()) != 1 || (c.master && c.part != 0) {
+		log.Printf("%s", c.Usage())
+		return subcommands.ExitUsageError
+	}
+	semitones, err := strconv.Atoi(f.Args()[0])
+	if err != nil {
+		log.Printf("invalid number of semitones: %v", err)
+		return subcommands.ExitUsageError
+	}
+	r, err := c.register()
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitUsageError
+	}
+	if c.live {
+		t := &transposeTransform{Semitones: semitones}
+		if c.part != 0 {
+			t.Channels = channelSet{c.part}
+		}
+		if err := runProxy(c.inputDevice, pipeline{t}); err != nil {
+			log.Printf("proxy failed: %v", err)
+			return subcommands.ExitFailure
+		}
+		return subcommands.ExitSuccess
+	}
+	if min, max := r.Range(); semitones < min || semitones > max {
+		log.Printf("can't transpose by %d semitones: must be in the range %d to %d", semitones, min, max)
+		return subcommands.ExitUsageError
+	}
+	msg, err := r.Set(deviceID(), semitones)
+	if err != nil {
+		log.Printf("%v", err)
+		return subcommands.ExitFailure
+	}
+	if c.checkpoint {
+		if err := saveCheckpoint(defaultCheckpointFile()); err != nil {
+			log.Printf("failed to save checkpoint: %v", err)
+			return subcommands.ExitFailure
+		}
+	}
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer out.Close()
+	if err := writeSysEx(out, msg); err != nil {
+		log.Printf("failed to write message to output: %v", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
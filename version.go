@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/google/subcommands"
+)
+
+// version is the release version, set at build time with
+// -ldflags "-X main.version=v1.2.3". If it isn't set, the module version
+// recorded by the Go toolchain is reported instead.
+var version = ""
+
+type versionCommand struct{}
+
+func (*versionCommand) Name() string             { return "version" }
+func (*versionCommand) Synopsis() string         { return "print version and build information" }
+func (*versionCommand) Usage() string            { return "version:\n" }
+func (*versionCommand) SetFlags(f *flag.FlagSet) {}
+
+// buildSettings returns the named settings recorded in the build info.
+func buildSettings(info *debug.BuildInfo) map[string]string {
+	result := map[string]string{}
+	for _, s := range info.Settings {
+		result[s.Key] = s.Value
+	}
+	return result
+}
+
+// versionString returns the version of the program.
+func versionString() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(unknown)"
+}
+
+func (*versionCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	fmt.Printf("sc55ctl %s\n", versionString())
+	fmt.Printf("built with %s for %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return subcommands.ExitSuccess
+	}
+	settings := buildSettings(info)
+	if rev, ok := settings["vcs.revision"]; ok {
+		if settings["vcs.modified"] == "true" {
+			rev += " (modified)"
+		}
+		fmt.Printf("revision %s\n", rev)
+	}
+	if t, ok := settings["vcs.time"]; ok {
+		fmt.Printf("committed %s\n", t)
+	}
+	return subcommands.ExitSuccess
+}
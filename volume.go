@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+var percentRE = regexp.MustCompile(`(\d+)%`)
+
+type followVolumeCommand struct {
+	interval    time.Duration
+	command     string
+	muteCommand string
+}
+
+func (*followVolumeCommand) Name() string { return "follow-volume" }
+func (*followVolumeCommand) Synopsis() string {
+	return "mirror the desktop audio volume to the SoundCanvas master volume"
+}
+func (*followVolumeCommand) Usage() string { return "" }
+
+func (c *followVolumeCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	setDisplayErrorFlags(f)
+	f.DurationVar(&c.interval, "interval", 200*time.Millisecond, "how often to poll the system volume; at most one update is sent per interval")
+	f.StringVar(&c.command, "volume_command", "pactl get-sink-volume @DEFAULT_SINK@", "command that prints the current volume as a percentage")
+	f.StringVar(&c.muteCommand, "mute_command", "pactl get-sink-mute @DEFAULT_SINK@", "command that prints \"yes\" when audio is muted; empty to ignore mute state")
+}
+
+func runCommand(command string) (string, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return "", fmt.Errorf("empty command")
+	}
+	out, err := exec.Command(args[0], args[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%q failed: %v", command, err)
+	}
+	return string(out), nil
+}
+
+// systemVolume returns the current system volume, scaled to the range of the
+// master-volume register.
+func (c *followVolumeCommand) systemVolume() (int, error) {
+	if c.muteCommand != "" {
+		out, err := runCommand(c.muteCommand)
+		if err != nil {
+			return 0, err
+		}
+		if strings.Contains(strings.ToLower(out), "yes") {
+			return 0, nil
+		}
+	}
+	out, err := runCommand(c.command)
+	if err != nil {
+		return 0, err
+	}
+	m := percentRE.FindStringSubmatch(out)
+	if m == nil {
+		return 0, fmt.Errorf("no volume percentage in output of %q: %q", c.command, out)
+	}
+	percent, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, err
+	}
+	// Values above 100% are possible with software amplification, but
+	// the SoundCanvas can't go any louder than its maximum.
+	value := percent * sc55.MasterVolume.Max / 100
+	if value > sc55.MasterVolume.Max {
+		value = sc55.MasterVolume.Max
+	}
+	return value, nil
+}
+
+func (c *followVolumeCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	out, err := openOutputStream()
+	if err != nil {
+		log.Printf("failed to open output stream: %v", err)
+		return subcommands.ExitFailure
+	}
+	defer displayPanics(out)
+	lastValue := -1
+	for {
+		value, err := c.systemVolume()
+		switch {
+		case err != nil:
+			log.Printf("failed to read system volume: %v", err)
+		case value != lastValue:
+			msg, err := sc55.MasterVolume.Set(deviceID(), value)
+			if err == nil {
+				err = writeSysEx(out, msg)
+			}
+			if err != nil {
+				log.Printf("failed to write message to output: %v", err)
+				displayError(out, errCodeWrite)
+				return subcommands.ExitFailure
+			}
+			lastValue = value
+		}
+		time.Sleep(c.interval)
+	}
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/fragglet/sc55ctl/sc55"
+	"github.com/google/subcommands"
+)
+
+// rxChannelOff is the rx-channel value that stops a part receiving on any
+// channel.
+const rxChannelOff = 16
+
+// wizardUseCase holds the effect settings for one of the wizard's use cases.
+type wizardUseCase struct {
+	description              string
+	reverbMacro, reverbLevel int
+	chorusLevel              int
+	reverbSend, chorusSend   int
+	// singleChannel layers all the parts on channel 1.
+	singleChannel bool
+}
+
+var wizardUseCases = map[string]wizardUseCase{
+	"dos": {
+		description: "DOS games: the GS defaults, which most games were written for",
+		reverbMacro: 4, reverbLevel: 64, chorusLevel: 64,
+		reverbSend: 40, chorusSend: 0,
+	},
+	"daw": {
+		description: "DAW: dry parts, so that effects can be added in the mix",
+		reverbMacro: 4, reverbLevel: 0, chorusLevel: 0,
+		reverbSend: 0, chorusSend: 0,
+	},
+	"live": {
+		description: "live keyboard: every part layered on channel 1, with plenty of reverb",
+		reverbMacro: 5, reverbLevel: 96, chorusLevel: 64,
+		reverbSend: 64, chorusSend: 32, singleChannel: true,
+	},
+}
+
+type wizardCommand struct {
+	output string
+	dryRun bool
+}
+
+func (*wizardCommand) Name() string { return "wizard" }
+func (*wizardCommand) Synopsis() string {
+	return "answer a few questions to generate and apply a settings file"
+}
+func (*wizardCommand) Usage() string {
+	return `wizard:
+Asks what the SoundCanvas will be used for, how many parts to use and
+which channels are drums, then writes a settings file and applies it.
+Press enter to accept the default answer shown in brackets.
+`
+}
+
+func (c *wizardCommand) SetFlags(f *flag.FlagSet) {
+	setCommonFlags(f)
+	f.StringVar(&c.output, "output", "sc55-settings.json", "file to save the generated settings to")
+	f.BoolVar(&c.dryRun, "dry_run", false, "only save the settings file, without applying it")
+}
+
+// prompter asks questions on the terminal.
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask prints a question and returns the answer, or the default if the
+// answer is empty.
+func (p *prompter) ask(question, def string) (string, error) {
+	fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	if !p.in.Scan() {
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", io.ErrUnexpectedEOF
+	}
+	answer := strings.TrimSpace(p.in.Text())
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// askUntil repeats a question until parse accepts the answer.
+func (p *prompter) askUntil(question, def string, parse func(string) error) error {
+	for {
+		answer, err := p.ask(question, def)
+		if err != nil {
+			return err
+		}
+		if err := parse(answer); err != nil {
+			fmt.Fprintf(p.out, "%v\n", err)
+			continue
+		}
+		return nil
+	}
+}
+
+// parseChannels parses a comma-separated list of MIDI channel numbers.
+func parseChannels(s string) (map[int]bool, error) {
+	result := map[int]bool{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" || field == "none" {
+			continue
+		}
+		ch, err := strconv.Atoi(field)
+		if err != nil || ch < 1 || ch > 16 {
+			return nil, fmt.Errorf("invalid channel %q, want 1 <= x <= 16", field)
+		}
+		result[ch] = true
+	}
+	return result, nil
+}
+
+// wizardSettings generates the settings for the given answers.
+func wizardSettings(u wizardUseCase, numParts int, drums map[int]bool) settings {
+	s := settings{
+		"reverb-macro": u.reverbMacro,
+		"reverb-level": u.reverbLevel,
+		"chorus-level": u.chorusLevel,
+	}
+	for i := 1; i <= sc55.PartCount(); i++ {
+		prefix := fmt.Sprintf("part-%d.", i)
+		channel := (i-1)%16 + 1
+		if u.singleChannel {
+			channel = 1
+		}
+		if i > numParts {
+			s[prefix+"rx-channel"] = rxChannelOff
+			continue
+		}
+		s[prefix+"rx-channel"] = channel - 1
+		s[prefix+"use-for-rhythm"] = 0
+		if drums[channel] {
+			s[prefix+"use-for-rhythm"] = 1
+		}
+		s[prefix+"reverb-send-level"] = u.reverbSend
+		s[prefix+"chorus-send-level"] = u.chorusSend
+	}
+	return s
+}
+
+func (c *wizardCommand) Execute(context.Context, *flag.FlagSet, ...interface{}) subcommands.ExitStatus {
+	p := &prompter{bufio.NewScanner(os.Stdin), os.Stdout}
+	for _, name := range []string{"dos", "daw", "live"} {
+		fmt.Printf("  %-5s %s\n", name, wizardUseCases[name].description)
+	}
+	var u wizardUseCase
+	numParts := 16
+	var drums map[int]bool
+	err := p.askUntil("What will the SoundCanvas be used for?", "dos", func(answer string) error {
+		var ok bool
+		u, ok = wizardUseCases[strings.ToLower(answer)]
+		if !ok {
+			return fmt.Errorf("unknown use case %q", answer)
+		}
+		return nil
+	})
+	if err == nil {
+		err = p.askUntil("How many parts?", strconv.Itoa(numParts), func(answer string) error {
+			n, err := strconv.Atoi(answer)
+			if err != nil || n < 1 || n > sc55.PartCount() {
+				return fmt.Errorf("invalid number of parts %q, want 1 <= x <= %d", answer, sc55.PartCount())
+			}
+			numParts = n
+			return nil
+		})
+	}
+	if err == nil {
+		def := "10"
+		if u.singleChannel {
+			def = "none"
+		}
+		err = p.askUntil("Which channels are drums?", def, func(answer string) error {
+			var err error
+			drums, err = parseChannels(answer)
+			return err
+		})
+	}
+	if err != nil {
+		log.Printf("failed to read answer: %v", err)
+		return subcommands.ExitFailure
+	}
+	sf := &settingsFile{Registers: wizardSettings(u, numParts, drums)}
+	if err := sf.save(c.output); err != nil {
+		log.Printf("failed to save settings: %v", err)
+		return subcommands.ExitFailure
+	}
+	log.Printf("settings saved to %s", c.output)
+	if c.dryRun {
+		return subcommands.ExitSuccess
+	}
+	return applySettings(sf, false, "")
+}